@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+const defaultScenario = "JOIN1"
+
+const usage = `Usage:
+  spanneranime list
+  spanneranime run [flags] [scenario]
+
+Commands:
+  list  print the available scenarios
+  run   open a window and play a scenario (default %s)
+
+Run "spanneranime run -h" for the flags of run.
+`
+
+// run executes the command line. For compatibility a bare scenario name,
+// optionally preceded by flags, is treated as "run <scenario>".
+func run(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			return listScenarios(os.Stdout)
+		case "run":
+			return runScenario(args[1:])
+		case "help", "-h", "-help", "--help":
+			fmt.Fprintf(os.Stdout, usage, defaultScenario)
+			return nil
+		}
+	}
+	return runScenario(args)
+}
+
+func listScenarios(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range scenarioNames() {
+		s, _ := lookupScenario(name)
+		fmt.Fprintf(tw, "%s\t%s\n", name, s.Description())
+	}
+	return tw.Flush()
+}
+
+func runScenario(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	width := fs.Int("width", screenWidth, "window width")
+	height := fs.Int("height", screenHeight, "window height")
+	speed := fs.Float64("speed", 1, fmt.Sprintf("playback speed (%g-%g)", sim.MinSpeed, sim.MaxSpeed))
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed for the generated data")
+	autoplay := fs.Bool("autoplay", false, "start the animation without waiting for Space")
+	data := fs.String("data", "", "YAML or JSON scenario file with the tables to animate")
+	join := fs.String("join", "inner", "JOIN type of the JOIN scenarios: inner, left, semi or anti")
+	groupBy := fs.String("group-by", "", "comma separated GROUP BY columns of the GROUP BY scenarios: item, userid or orderid (default item)")
+	agg := fs.String("agg", "sum", "aggregate function of the GROUP BY scenarios: sum, count, avg, min, max or count-distinct")
+	var topology sim.Topology
+	fs.IntVar(&topology.Users, "user-splits", 0, "number of User splits (0 for the scenario default)")
+	fs.IntVar(&topology.Orders, "order-splits", 0, "number of Order splits (0 for the scenario default)")
+	fs.IntVar(&topology.Index, "index-splits", 0, "number of Index splits (0 for the scenario default)")
+	fs.IntVar(&topology.FanIn, "fan-in", 0, "number of servers one aggregation server merges in GROUPBY1 and GROUPBY4 (0 for the scenario default)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: spanneranime run [flags] [scenario]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	name := ""
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+		// Allow flags after the scenario name as well.
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected arguments: %v", fs.Args())
+		}
+	}
+	if *width <= 0 || *height <= 0 {
+		return fmt.Errorf("invalid window size %dx%d", *width, *height)
+	}
+	if *speed < sim.MinSpeed || *speed > sim.MaxSpeed {
+		return fmt.Errorf("speed must be between %g and %g, got %g", sim.MinSpeed, sim.MaxSpeed, *speed)
+	}
+	joinType, err := sim.ParseJoinType(*join)
+	if err != nil {
+		return err
+	}
+	aggregate, err := sim.ParseAggregate(*agg)
+	if err != nil {
+		return err
+	}
+	var columns []sim.Column
+	if *groupBy != "" {
+		if columns, err = sim.ParseGroupBy(*groupBy); err != nil {
+			return err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	var dataset *sim.Dataset
+	if *data != "" {
+		d, err := sim.LoadDataset(*data, rng)
+		if err != nil {
+			return err
+		}
+		dataset = d
+		if name == "" {
+			name = d.Scenario
+		}
+	}
+	if name == "" {
+		name = defaultScenario
+	}
+
+	g, err := NewGame(name, rng, dataset, topology, columns, aggregate)
+	if err != nil {
+		if _, lookupErr := lookupScenario(name); lookupErr != nil {
+			return fmt.Errorf("%w (run \"spanneranime list\" for the available scenarios)", err)
+		}
+		return err
+	}
+	g.engine.Scheduler.SetSpeed(*speed)
+	g.engine.JoinType = joinType
+	if *autoplay {
+		g.engine.AutoStart = true
+	}
+	log.Printf("scenario: %s, seed: %d", name, *seed)
+
+	ebiten.SetWindowSize(*width, *height)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle("Spanner Distributed JOIN Animation")
+	return ebiten.RunGame(g)
+}
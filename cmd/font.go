@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// fontSize is the size of all text on the design canvas.
+const fontSize = 20
+
+// mplus1pRegular is the M+ 1p font, which covers Latin and Japanese. See
+// fonts/LICENSE_E for its license.
+//
+//go:embed fonts/mplus-1p-regular.ttf
+var mplus1pRegular []byte
+
+var fontSource = func() *text.GoTextFaceSource {
+	s, err := text.NewGoTextFaceSource(bytes.NewReader(mplus1pRegular))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}()
+
+// fontFaces caches one face per pixel size, so resizing the window does not
+// create a face on every frame.
+type fontFaces map[float64]*text.GoTextFace
+
+// face returns the face for size pixels, rounded to a quarter pixel.
+func (f fontFaces) face(size float64) *text.GoTextFace {
+	size = math.Round(size*4) / 4
+	face, ok := f[size]
+	if !ok {
+		face = &text.GoTextFace{Source: fontSource, Size: size}
+		f[size] = face
+	}
+	return face
+}
+
+// drawText draws str with its top left corner at x, y in the font size of the
+// current layout.
+func (g *Game) drawText(screen *ebiten.Image, str string, x, y float32, clr color.Color) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(clr)
+	text.Draw(screen, str, g.faces.face(fontSize*float64(g.layout.Scale())), op)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return groupby1{&sim.GROUPBY1{}} })
+}
+
+type groupby1 struct {
+	*sim.GROUPBY1
+}
+
+// Bottom layer splits show a column header in row 0 and their orders below.
+// The tiers above have a row for every group they can receive.
+func (s groupby1) Tables() []layout.Table {
+	return tierTables(s.GROUPBY1, func(from, to int) int {
+		return s.Groups(s.OrderMachines[from:to]...)
+	})
+}
+
+// tierTables places the splits at the bottom and stacks the mid-tier levels
+// and the top tier above them, shrinking the tiers when the tree is too deep
+// to fit. rows returns the number of rows of a server over splits from to
+// to.
+func tierTables(s *sim.GROUPBY1, rows func(from, to int) int) []layout.Table {
+	bottom := splitRows(s.OrderMachines)
+	for i := range bottom {
+		bottom[i]++
+	}
+	tables := []layout.Table{
+		{Name: sim.TableOrders, Area: layout.Rect{X: 50, Y: 650, W: 1550, H: 300}, Horizontal: true, Rows: bottom, Gap: 50, Header: 40, RowHeight: 25},
+	}
+
+	levels := s.MidLevels()
+	k := min(1, 530/float32(250+200*len(levels)))
+	y := float32(600)
+	for l, n := range levels {
+		middle := make([]int, n)
+		for m := range middle {
+			middle[m] = rows(s.Splits(l, m))
+		}
+		y -= 150 * k
+		tables = append(tables, layout.Table{Name: sim.MidTierTable(l), Area: layout.Rect{X: 0, Y: y, W: 1600, H: 150 * k}, Horizontal: true, Rows: middle, Gap: 50, Header: 40, RowHeight: 25, MaxWidth: 400})
+		y -= 50 * k
+	}
+	y -= 250 * k
+	return append(tables, layout.Table{Name: sim.TableTopTier, Area: layout.Rect{X: 600, Y: y, W: 400, H: 250 * k}, Rows: []int{rows(0, len(s.OrderMachines))}, Header: 40, RowHeight: 25})
+}
+
+func (s groupby1) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	r := l.Split(ep.Table, ep.Split)
+	if strings.HasPrefix(ep.Table, sim.TableMidTier) && outgoing {
+		return r.Anchor(layout.Top)
+	}
+	if ep.Table == sim.TableOrders {
+		return r.Anchor(layout.Top) // Bottom layer
+	}
+	return r.Anchor(layout.Bottom)
+}
+
+// midTierTitle names server i of mid-tier level l, with the level only if
+// there are several.
+func midTierTitle(s *sim.GROUPBY1, l, i int) string {
+	if len(s.MiddleLayerResults) == 1 {
+		return fmt.Sprintf("Mid-Tier %d", i+1)
+	}
+	return fmt.Sprintf("Mid-Tier %d-%d", l+1, i+1)
+}
+
+// drawTree describes the shape of the aggregation tree below the query.
+func drawTree(g *Game, screen *ebiten.Image, s *sim.GROUPBY1) {
+	hops := len(s.MiddleLayerResults) + 1
+	inputs := len(s.OrderMachines)
+	if levels := s.MidLevels(); len(levels) > 0 {
+		inputs = levels[len(levels)-1]
+	}
+	x, y := g.layout.Point(50, 40)
+	g.drawText(screen, fmt.Sprintf("Fan-in %d: %d hops to the Top-Tier, which merges %d inputs", s.FanIn(), hops, inputs), x, y, color.White)
+}
+
+// beforeAggregation reports whether the splits still show their rows rather
+// than their aggregated results.
+func beforeAggregation(step sim.Step) bool {
+	return step == sim.StepIdle || step == sim.StepGroupByBottomLayer
+}
+
+func (s groupby1) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	g.drawCounter(screen, g.groupByQuery(s.GroupBy(), s.Aggregate()))
+	drawTree(g, screen, s.GROUPBY1)
+	// Bottom Layer (one machine per split)
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Split %d", i+1))
+		if beforeAggregation(e.Step) {
+			g.drawLabel(screen, strings.Join([]string{e.Schema.OrderID, e.Schema.OrderUserID, e.Schema.Item, e.Schema.Price}, ","), g.layout.Row(sim.TableOrders, i, 0), color.White)
+			for j, order := range machine {
+				g.drawLabel(screen, fmt.Sprintf("%d,%d,%s,%d", order.OrderID, order.UserID, order.Item, order.Price), g.layout.Row(sim.TableOrders, i, j+1), color.White)
+			}
+		} else {
+			for j, res := range s.BottomLayerResults[i] {
+				g.drawLabel(screen, partialLabel(s.Aggregate(), res), g.layout.Row(sim.TableOrders, i, j), color.RGBA{R: 0xff, G: 0xff, A: 0xff})
+			}
+		}
+	}
+
+	// Middle Layers (one machine per FanIn machines of the level below)
+	for l, level := range s.MiddleLayerResults {
+		for i, results := range level {
+			g.drawBox(screen, g.layout.Split(sim.MidTierTable(l), i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, midTierTitle(s.GROUPBY1, l, i))
+			if l < s.Level {
+				for j, res := range results {
+					g.drawLabel(screen, partialLabel(s.Aggregate(), res), g.layout.Row(sim.MidTierTable(l), i, j), color.White)
+				}
+			}
+		}
+	}
+
+	// Top Layer (1 machine)
+	g.drawBox(screen, g.layout.Split(sim.TableTopTier, 0), color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, "Top-Tier")
+	for i, res := range s.TopLayerResult {
+		g.drawLabel(screen, resultLabel(s.Aggregate(), res), g.layout.Row(sim.TableTopTier, 0, i), color.White)
+	}
+
+	// Packets
+	for _, p := range e.Packets {
+		if p.Active {
+			g.drawPacket(screen, p, 10)
+		}
+	}
+
+	if e.Step == sim.StepIdle {
+		g.drawStartHint(screen, 594)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return groupby2{&sim.GROUPBY2{}} })
+}
+
+type groupby2 struct {
+	*sim.GROUPBY2
+}
+
+// Splits show a column header in row 0 and their orders below.
+func (s groupby2) Tables() []layout.Table {
+	rows := splitRows(s.OrderMachines)
+	for i := range rows {
+		rows[i]++
+	}
+	return []layout.Table{
+		{Name: sim.TableOrders, Area: layout.Rect{X: 50, Y: 50, W: 875, H: 900}, Horizontal: true, Rows: rows, Gap: 25, Header: 40, RowHeight: 25},
+		{Name: sim.TableTopTier, Area: layout.Rect{X: 1000, Y: 50, W: 550, H: 900}, Header: 65, RowHeight: 25},
+	}
+}
+
+// GROUPBY2 sends no packets.
+func (s groupby2) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return 0, 0
+}
+
+func (s groupby2) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	g.drawCounter(screen, g.groupByQuery(s.GroupBy(), s.Aggregate()))
+	// Left side: the splits
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Split %d", i+1))
+		g.drawLabel(screen, g.groupedHeader(s.GroupBy()), g.layout.Row(sim.TableOrders, i, 0), color.White)
+		for j, order := range machine {
+			g.drawLabel(screen, groupedRow(s.GroupBy(), order), g.layout.Row(sim.TableOrders, i, j+1), color.White)
+		}
+	}
+
+	// Right side: Final Result
+	g.drawBox(screen, g.layout.Split(sim.TableTopTier, 0), color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, "Final Aggregation Result")
+
+	// Draw highlights and results
+	if e.Step == sim.StepParallelAggregation {
+		// Draw highlights
+		for _, locations := range s.GroupLocations {
+			if s.ParallelScanIndex < len(locations) {
+				loc := locations[s.ParallelScanIndex]
+				r := g.layout.Row(sim.TableOrders, loc.Split, loc.Row+1)
+				vector.DrawFilledRect(screen, r.X, r.Y, r.W, r.H, color.RGBA{R: 0xff, G: 0xff, A: 0x80}, false)
+			}
+		}
+		// Draw running totals
+		for i, res := range s.ParallelAggregations {
+			g.drawLabel(screen, partialLabel(s.Aggregate(), res)+" (Processing...)", g.layout.Row(sim.TableTopTier, 0, i), color.White)
+		}
+
+	} else if e.Step == sim.StepFinished || e.Step == sim.StepG2PauseBeforeRestart {
+		// Draw final results
+		for i, res := range s.TopLayerResult {
+			g.drawLabel(screen, resultLabel(s.Aggregate(), res), g.layout.Row(sim.TableTopTier, 0, i), color.White)
+		}
+	}
+
+	if e.Step == sim.StepIdle {
+		g.drawStartHint(screen, 594)
+	}
+}
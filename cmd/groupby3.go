@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return groupby3{&sim.GROUPBY3{}} })
+}
+
+type groupby3 struct {
+	*sim.GROUPBY3
+}
+
+// Names of the four panels of GROUPBY3. Each half has its input on the left
+// and its aggregation state on the right.
+const (
+	hashInput    = "HashInput"
+	hashTable    = "HashTable"
+	streamInput  = "StreamInput"
+	streamOutput = "StreamOutput"
+)
+
+// The hash aggregate takes the left half and the stream aggregate the right
+// half. Inputs show a column header in row 0 and their orders below.
+func (s groupby3) Tables() []layout.Table {
+	rows := []int{len(s.Orders) + 1}
+	groups := []int{s.Groups(s.Orders) + 1}
+	return []layout.Table{
+		{Name: hashInput, Area: layout.Rect{X: 50, Y: 50, W: 350, H: 900}, Rows: rows, Header: 40, RowHeight: 25},
+		{Name: hashTable, Area: layout.Rect{X: 425, Y: 50, W: 325, H: 900}, Rows: groups, Header: 65, RowHeight: 25},
+		{Name: streamInput, Area: layout.Rect{X: 850, Y: 50, W: 350, H: 900}, Rows: rows, Header: 40, RowHeight: 25},
+		{Name: streamOutput, Area: layout.Rect{X: 1225, Y: 50, W: 325, H: 900}, Rows: groups, Header: 65, RowHeight: 25},
+	}
+}
+
+// GROUPBY3 sends no packets.
+func (s groupby3) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return 0, 0
+}
+
+func (s groupby3) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawInput(g, screen, hashInput, "Hash Aggregate: input", s.Orders)
+	s.drawInput(g, screen, streamInput, "Stream Aggregate: input sorted by "+columnNames(e.Schema, s.GroupBy()), s.SortedOrders)
+	s.drawHashTable(g, screen)
+	s.drawStream(g, screen)
+
+	state := 0
+	if s.StreamOpen {
+		state = 1
+	}
+	g.drawCounter(screen, fmt.Sprintf("%s / Hash table: %d entries / Stream state: %d group", g.groupByQuery(s.GroupBy(), s.Aggregate()), len(s.HashTable), state))
+}
+
+// drawInput lists orders in a panel and highlights the row the aggregate
+// reads next.
+func (s groupby3) drawInput(g *Game, screen *ebiten.Image, name, title string, orders []sim.Order) {
+	e := g.engine
+	g.drawBox(screen, g.layout.Split(name, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, title)
+	g.drawLabel(screen, g.groupedHeader(s.GroupBy()), g.layout.Row(name, 0, 0), color.White)
+	for j, o := range orders {
+		var c color.Color = color.White
+		if e.Step == sim.StepAggregating && j < s.ScanIndex {
+			c = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+		}
+		g.drawLabel(screen, groupedRow(s.GroupBy(), o), g.layout.Row(name, 0, j+1), c)
+	}
+	if e.Step == sim.StepAggregating && s.ScanIndex < len(orders) {
+		r := g.layout.Row(name, 0, s.ScanIndex+1)
+		vector.DrawFilledRect(screen, r.X, r.Y, r.W, r.H, color.RGBA{R: 0xff, G: 0xff, A: 0x80}, false)
+	}
+}
+
+// drawHashTable shows the hash table while it grows and the result once
+// the input has ended.
+func (s groupby3) drawHashTable(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	g.drawBox(screen, g.layout.Split(hashTable, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, "Hash table (emits at end)")
+	if s.HashResult != nil {
+		for i, res := range s.HashResult {
+			g.drawLabel(screen, resultLabel(s.Aggregate(), res), g.layout.Row(hashTable, 0, i), color.RGBA{R: 0xff, G: 0xff, A: 0xff})
+		}
+		return
+	}
+	for i, entry := range s.HashTable {
+		var c color.Color = color.White
+		if e.Step == sim.StepAggregating && i == s.HashUpdated {
+			c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+		}
+		g.drawLabel(screen, partialLabel(s.Aggregate(), entry)+" (Processing...)", g.layout.Row(hashTable, 0, i), c)
+	}
+}
+
+// drawStream shows the groups the stream aggregate has emitted followed by
+// the one group it is still adding to.
+func (s groupby3) drawStream(g *Game, screen *ebiten.Image) {
+	g.drawBox(screen, g.layout.Split(streamOutput, 0), color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, "Emitted groups")
+	for i, res := range s.StreamResult {
+		g.drawLabel(screen, resultLabel(s.Aggregate(), res), g.layout.Row(streamOutput, 0, i), color.RGBA{R: 0xff, G: 0xff, A: 0xff})
+	}
+	if s.StreamOpen {
+		g.drawLabel(screen, partialLabel(s.Aggregate(), s.StreamGroup)+" (Processing...)", g.layout.Row(streamOutput, 0, len(s.StreamResult)), color.White)
+	}
+}
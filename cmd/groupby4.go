@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return groupby4{&sim.GROUPBY4{}} })
+}
+
+type groupby4 struct {
+	*sim.GROUPBY4
+}
+
+// GROUPBY4 uses the tiers of GROUPBY1. A server above the splits receives
+// at most TopK rows from each of its children.
+func (s groupby4) Tables() []layout.Table {
+	return tierTables(&s.GROUPBY1, func(from, to int) int {
+		return min(s.Groups(s.OrderMachines[from:to]...), s.FanIn()*sim.TopK)
+	})
+}
+
+func (s groupby4) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return groupby1{&s.GROUPBY1}.Anchor(l, ep, outgoing)
+}
+
+func (s groupby4) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	schema := e.Schema
+	sum := fmt.Sprintf("SUM(%s)", schema.Price)
+	keys := columnNames(schema, s.GroupBy())
+	g.drawCounter(screen, fmt.Sprintf("SELECT %s, %s FROM %s GROUP BY %s HAVING %s >= %d ORDER BY %s DESC LIMIT %d", keys, sum, schema.OrderTable, keys, sum, sim.HavingMinSum, sum, sim.TopK))
+	s.drawShipped(g, screen)
+
+	// Bottom Layer (one machine per split)
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Split %d", i+1))
+		if beforeAggregation(e.Step) {
+			g.drawLabel(screen, strings.Join([]string{schema.OrderID, schema.OrderUserID, schema.Item, schema.Price}, ","), g.layout.Row(sim.TableOrders, i, 0), color.White)
+			for j, order := range machine {
+				g.drawLabel(screen, fmt.Sprintf("%d,%d,%s,%d", order.OrderID, order.UserID, order.Item, order.Price), g.layout.Row(sim.TableOrders, i, j+1), color.White)
+			}
+		} else {
+			s.drawRanked(g, screen, sim.TableOrders, i, s.LocalGroups[i], s.BottomLayerResults[i])
+		}
+	}
+
+	// Middle Layers
+	for l, level := range s.MiddleLayerResults {
+		for i, results := range level {
+			g.drawBox(screen, g.layout.Split(sim.MidTierTable(l), i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, midTierTitle(&s.GROUPBY1, l, i))
+			if l < s.Level {
+				s.drawRanked(g, screen, sim.MidTierTable(l), i, s.MiddleReceived[l][i], results)
+			}
+		}
+	}
+
+	// Top Layer
+	g.drawBox(screen, g.layout.Split(sim.TableTopTier, 0), color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, "Top-Tier")
+	s.drawRanked(g, screen, sim.TableTopTier, 0, s.TopReceived, s.TopLayerResult)
+
+	for _, p := range e.Packets {
+		if p.Active {
+			g.drawPacket(screen, p, 10)
+		}
+	}
+
+	if e.Step == sim.StepIdle {
+		g.drawStartHint(screen, 594)
+	}
+}
+
+// drawShipped counts the rows every tier has shipped so far against the
+// groups the splits have.
+func (s groupby4) drawShipped(g *Game, screen *ebiten.Image) {
+	if beforeAggregation(g.engine.Step) {
+		return
+	}
+	groups, shipped := 0, 0
+	for i := range s.LocalGroups {
+		groups += len(s.LocalGroups[i])
+		shipped += len(s.BottomLayerResults[i])
+	}
+	str := fmt.Sprintf("Rows shipped: %d of %d groups by the splits", shipped, groups)
+	for l := 0; l < s.Level; l++ {
+		shipped = 0
+		for _, results := range s.MiddleLayerResults[l] {
+			shipped += len(results)
+		}
+		if len(s.MiddleLayerResults) == 1 {
+			str += fmt.Sprintf(", %d by the Mid-Tier", shipped)
+		} else {
+			str += fmt.Sprintf(", %d by Mid-Tier level %d", shipped, l+1)
+		}
+	}
+	x, y := g.layout.Point(50, 40)
+	g.drawText(screen, str, x, y, color.White)
+}
+
+// drawRanked lists the rows of a tier by SUM(Price). The kept rows, a prefix
+// of rows, are highlighted and the others show the clause that cut them.
+func (s groupby4) drawRanked(g *Game, screen *ebiten.Image, table string, split int, rows, kept []sim.AggregationResult) {
+	for j, res := range rows {
+		label := fmt.Sprintf("%s: %d", keyLabel(res), res.Sum)
+		var c color.Color = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+		switch {
+		case res.Sum < sim.HavingMinSum:
+			label += " (HAVING)"
+			c = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+		case j >= len(kept):
+			label += " (LIMIT)"
+			c = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+		}
+		g.drawLabel(screen, label, g.layout.Row(table, split, j), c)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join1{&sim.JOIN1{}} })
+}
+
+type join1 struct {
+	*sim.JOIN1
+}
+
+func (s join1) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 450}, Rows: []int{len(s.Users)}, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 550, Y: 50, W: 500, H: 450}, Rows: []int{len(s.Orders)}, Header: 60, RowHeight: 30},
+		joinedTable(520),
+	}
+}
+
+func (s join1) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	if ep.Table == sim.TableUsers {
+		return l.Row(ep.Table, 0, ep.Row).Anchor(layout.Right)
+	}
+	return l.Row(ep.Table, 0, ep.Row).Anchor(layout.Left)
+}
+
+func (s join1) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	if e.Step == sim.StepResponding || e.Step == sim.StepScanningOrderTable {
+		g.drawPacket(screen, e.Packets[0], 5)
+	}
+	if e.Step == sim.StepIdle {
+		g.drawStartHint(screen, 393)
+	}
+}
+
+func (s join1) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	// User Table
+	g.drawBox(screen, g.layout.Split(sim.TableUsers, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, e.Schema.UserTable+" Table")
+	for i, u := range s.Users {
+		var c color.Color = color.White
+		if e.Step > sim.StepIdle && s.CurrentUserIndex == i {
+			c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+		}
+		g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, 0, i), c)
+	}
+
+	// Order Table
+	g.drawBox(screen, g.layout.Split(sim.TableOrders, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, e.Schema.OrderTable+" Table")
+	for i, o := range s.Orders {
+		var c color.Color = color.White
+		if e.Step != sim.StepIdle && i < s.OrderScanIndex && o.UserID == s.Users[s.CurrentUserIndex].UserID {
+			c = color.RGBA{R: 0xff, G: 0xff, A: 0xff} // Yellow for joined
+		} else if e.Step == sim.StepScanningOrderTable && i == s.OrderScanIndex {
+			c = color.RGBA{B: 0xff, A: 0xff} // Blue for scanning
+		}
+		g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, 0, i), c)
+	}
+}
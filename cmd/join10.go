@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join10{&sim.JOIN10{}} })
+}
+
+type join10 struct {
+	*sim.JOIN10
+}
+
+// Items sits above the Order splits it is broadcast to, and the root below
+// them. Every split has a header row.
+func (s join10) Tables() []layout.Table {
+	rows := splitRows(s.OrderMachines)
+	for i := range rows {
+		rows[i]++
+	}
+	return []layout.Table{
+		{Name: sim.TableItems, Area: layout.Rect{X: 600, Y: 40, W: 400, H: 220}, Rows: []int{len(s.Items) + 1}, Header: 50, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 50, Y: 320, W: 1500, H: 420}, Horizontal: true, Rows: rows, Gap: 30, Header: 50, RowHeight: 30},
+		{Name: sim.TableTopTier, Area: layout.Rect{X: 450, Y: 780, W: 700, H: 210}, Rows: []int{len(s.OrderMachines) + 1}, Header: 50, RowHeight: 30},
+	}
+}
+
+func (s join10) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	r := l.Split(ep.Table, ep.Split)
+	if ep.Table == sim.TableItems || ep.Table == sim.TableOrders && outgoing {
+		return r.Anchor(layout.Bottom)
+	}
+	return r.Anchor(layout.Top)
+}
+
+func (s join10) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawCounter(screen, fmt.Sprintf("RPCs: %d", len(e.RPCs)))
+	if e.Step == sim.StepBroadcastArrive || e.Step == sim.StepUnionArrive {
+		// Packets carry the whole Items table down and the partial results
+		// up.
+		for _, p := range e.Packets {
+			if p.Active {
+				g.drawPacket(screen, p, 8)
+			}
+		}
+	}
+}
+
+// inResult reports whether split i has added the order with orderID to its
+// partial result.
+func (s join10) inResult(i, orderID int) bool {
+	for _, row := range s.Partials[i] {
+		if row.Order.OrderID == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s join10) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	highlight := color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+
+	// Items
+	g.drawBox(screen, g.layout.Split(sim.TableItems, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, "Items (small table)")
+	g.drawLabel(screen, e.Schema.Item+","+e.Schema.Price, g.layout.Row(sim.TableItems, 0, 0), color.White)
+	for j, item := range s.Items {
+		g.drawLabel(screen, fmt.Sprintf("%s,%d", item.Item, item.Price), g.layout.Row(sim.TableItems, 0, j+1), color.White)
+	}
+
+	// Order Machines, each joining with its own copy of Items
+	for i, machine := range s.OrderMachines {
+		title := fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1)
+		header := e.Schema.OrderID + "," + e.Schema.Item
+		if s.HasItems[i] {
+			title += " + Items"
+			header += "," + e.Schema.Price
+		}
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, title)
+		g.drawLabel(screen, header, g.layout.Row(sim.TableOrders, i, 0), color.White)
+		for j, o := range machine {
+			var c color.Color = color.White
+			label := fmt.Sprintf("%d,%s", o.OrderID, o.Item)
+			if j < s.ScanIndex[i] {
+				price := "NULL"
+				if item, ok := s.LookupItem(o.Item); ok {
+					price = fmt.Sprint(item.Price)
+				}
+				label += "," + price
+				c = consumedColor
+				if s.inResult(i, o.OrderID) {
+					c = highlight
+				}
+			} else if e.Step == sim.StepLocalJoin && j == s.ScanIndex[i] {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, label, g.layout.Row(sim.TableOrders, i, j+1), c)
+		}
+	}
+
+	// Root
+	g.drawBox(screen, g.layout.Split(sim.TableTopTier, 0), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("Root: %s JOIN Result (UNION ALL)", e.JoinType))
+	if e.Step != sim.StepPauseBeforeRestart {
+		return
+	}
+	for i, partial := range s.Partials {
+		g.drawLabel(screen, fmt.Sprintf("%s Machine %d: %d rows", e.Schema.OrderTable, i+1, len(partial)), g.layout.Row(sim.TableTopTier, 0, i), color.White)
+	}
+	g.drawLabel(screen, fmt.Sprintf("Total: %d rows", len(s.Result)), g.layout.Row(sim.TableTopTier, 0, len(s.Partials)), highlight)
+}
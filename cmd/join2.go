@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join2{&sim.JOIN2{}} })
+}
+
+type join2 struct {
+	*sim.JOIN2
+}
+
+func (s join2) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 750, Y: 50, W: 550, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+func (s join2) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	if ep.Table == sim.TableUsers {
+		return l.Row(ep.Table, ep.Split, ep.Row).Anchor(layout.Right)
+	}
+	return l.Row(ep.Table, ep.Split, ep.Row).Anchor(layout.Left)
+}
+
+func (s join2) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	if e.Step == sim.StepResponding || e.Step == sim.StepScanningOrderTable || e.Step == sim.StepRespondingMove {
+		for _, p := range e.Packets {
+			g.drawPacket(screen, p, 5)
+		}
+	}
+}
+
+// scanned reports whether the user of split userIndex has scanned row j of
+// order machine i.
+func (s join2) scanned(userIndex, i, j int) bool {
+	m := s.OrderScanMachineIndex[userIndex]
+	return i < m || i == m && j < s.OrderScanIndex[userIndex]
+}
+
+func (s join2) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	// User Machines
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step > sim.StepIdle && s.CurrentUserIndex == j {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Order Machines
+	for i, machine := range s.OrderMachines { // i is the order machine index
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1))
+		for j, o := range machine { // j is the row index
+			var c color.Color = color.White
+
+			// Check for highlighting for every user
+			isScanning := false
+			isFound := false
+
+			for userIndex := range s.UserMachines {
+				// Check if this row is being scanned by this user
+				if e.Step == sim.StepScanningOrderTable && !s.ScanDone[userIndex] && s.OrderScanMachineIndex[userIndex] == i && s.OrderScanIndex[userIndex] == j {
+					isScanning = true
+				}
+				// Check if this row has already been joined with this user
+				if e.Step != sim.StepIdle && s.scanned(userIndex, i, j) && o.UserID == s.UserMachines[userIndex][s.CurrentUserIndex].UserID {
+					isFound = true
+				}
+			}
+
+			if isFound {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff} // Yellow
+			} else if isScanning {
+				c = color.RGBA{B: 0xff, A: 0xff} // Blue
+			}
+
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
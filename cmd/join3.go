@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join3{&sim.JOIN3{}} })
+}
+
+type join3 struct {
+	*sim.JOIN3
+}
+
+func (s join3) Tables() []layout.Table {
+	return indexJoinTables(s.UserMachines, s.IndexMachines, s.OrderMachines)
+}
+
+func (s join3) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return indexJoinAnchor(l, ep, outgoing)
+}
+
+// indexJoinTables places the User, Index and Order splits of the JOINs
+// through the secondary index side by side.
+func indexJoinTables(users [][]sim.User, index [][]sim.IndexEntry, orders [][]sim.Order) []layout.Table {
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(users), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableIndex, Area: layout.Rect{X: 550, Y: 50, W: 400, H: 550}, Rows: splitRows(index), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 1050, Y: 50, W: 500, H: 550}, Rows: splitRows(orders), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+// indexJoinAnchor lets packets flow from left to right through the tables of
+// indexJoinTables.
+func indexJoinAnchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	row := l.Row(ep.Table, ep.Split, ep.Row)
+	switch {
+	case ep.Table == sim.TableUsers:
+		return row.Anchor(layout.Right)
+	case ep.Table == sim.TableIndex && outgoing:
+		return row.Anchor(layout.Right)
+	default:
+		return row.Anchor(layout.Left)
+	}
+}
+
+// isCurrent reports whether any user is at row j of machine i.
+func isCurrent(cursors [][]sim.Cursor, i, j int) bool {
+	for _, user := range cursors {
+		for _, c := range user {
+			if c.Split == i && c.Row == j {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s join3) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	g.drawCounter(screen, fmt.Sprintf("RPCs: %d", len(e.RPCs)))
+	if e.Step == sim.StepUserToIndexResponse || e.Step == sim.StepIndexToOrderResponse {
+		for _, p := range e.Packets {
+			if p.Active {
+				g.drawPacket(screen, p, 5)
+			}
+		}
+	}
+}
+
+func (s join3) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	// User Machines
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && s.CurrentUserIndex == j {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Index Machines
+	for i, machine := range s.IndexMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableIndex, i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("Index Machine %d", i+1))
+		for j, entry := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && e.Step != sim.StepUserToIndexRequest && isCurrent(s.IndexEntries, i, j) {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.indexLabel(entry), g.layout.Row(sim.TableIndex, i, j), c)
+		}
+	}
+
+	// Order Machines
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if e.Step == sim.StepIndexToOrderResponse && isCurrent(s.OrderRows, i, j) {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join4{&sim.JOIN4{}} })
+}
+
+type join4 struct {
+	*sim.JOIN4
+}
+
+// tableMachines frames the User and Order splits stored on the same machine.
+const tableMachines = "Machines"
+
+// The User and Order columns are placed as in JOIN2, inside one frame per
+// machine.
+func (s join4) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: tableMachines, Area: layout.Rect{X: 40, Y: 40, W: 1270, H: 570}, Rows: make([]int, len(s.UserMachines)), Gap: 30},
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 750, Y: 50, W: 550, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+// JOIN4 sends no packets.
+func (s join4) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return 0, 0
+}
+
+func (s join4) Draw(g *Game, screen *ebiten.Image) {
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+}
+
+func (s join4) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	for i := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(tableMachines, i), color.RGBA{R: 0x20, G: 0x50, B: 0x20, A: 0xff}, "")
+	}
+
+	// User rows
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Machine %d: %s", i+1, e.Schema.UserTable))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step > sim.StepIdle && s.CurrentUserIndex == j {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Order rows, interleaved in the User rows of the same machine
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Machine %d: %s (interleaved)", i+1, e.Schema.OrderTable))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if e.Step > sim.StepIdle && o.UserID == s.UserMachines[i][s.CurrentUserIndex].UserID {
+				if j < s.OrderScanIndex[i] {
+					c = color.RGBA{R: 0xff, G: 0xff, A: 0xff} // Yellow for joined
+				} else if j == s.OrderScanIndex[i] && !s.ScanDone[i] {
+					c = color.RGBA{B: 0xff, A: 0xff} // Blue for scanning
+				}
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
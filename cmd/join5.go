@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join5{&sim.JOIN5{}} })
+}
+
+type join5 struct {
+	*sim.JOIN5
+}
+
+// The coordinator's buckets sit between the User and Order splits. Each
+// bucket has room for the users that hash to it.
+func (s join5) Tables() []layout.Table {
+	buckets := make([]int, sim.HashBuckets)
+	for _, machine := range s.UserMachines {
+		for _, u := range machine {
+			buckets[s.Bucket(u.UserID)]++
+		}
+	}
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableCoordinator, Area: layout.Rect{X: 550, Y: 50, W: 400, H: 550}, Rows: buckets, Gap: 20, Header: 40, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 1050, Y: 50, W: 500, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+// Users are inserted into a row of their bucket, Orders probe the whole bucket.
+func (s join5) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	switch {
+	case ep.Table == sim.TableUsers:
+		return l.Row(ep.Table, ep.Split, ep.Row).Anchor(layout.Right)
+	case ep.Table == sim.TableCoordinator && ep.Row >= 0:
+		return l.Row(ep.Table, ep.Split, ep.Row).Anchor(layout.Left)
+	case ep.Table == sim.TableCoordinator:
+		return l.Split(ep.Table, ep.Split).Anchor(layout.Right)
+	default:
+		return l.Row(ep.Table, ep.Split, ep.Row).Anchor(layout.Left)
+	}
+}
+
+func (s join5) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	building := e.Step == sim.StepBuildSend || e.Step == sim.StepBuildArrive
+	probing := e.Step == sim.StepProbeSend || e.Step == sim.StepProbeArrive
+	if building || probing {
+		phase := "Build phase: Users -> hash table"
+		if probing {
+			phase = "Probe phase: Orders -> hash table"
+		}
+		x, y := g.layout.Point(550, 10)
+		g.drawText(screen, phase, x, y, color.White)
+	}
+	s.drawTables(g, screen, building, probing)
+	g.drawJoinedTable(screen)
+	for _, p := range e.Packets {
+		if p.Active {
+			g.drawPacket(screen, p, 5)
+		}
+	}
+}
+
+func (s join5) drawTables(g *Game, screen *ebiten.Image, building, probing bool) {
+	e := g.engine
+	// User Machines
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if building && j == s.BuildIndex[i] {
+				c = color.RGBA{B: 0xff, A: 0xff} // Blue for sending
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Hash table buckets on the coordinator
+	for b, bucket := range s.Buckets {
+		var c color.Color = color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}
+		for _, probe := range s.ProbeBucket {
+			if probing && probe == b {
+				c = color.RGBA{R: 0x60, G: 0x60, B: 0x30, A: 0xff}
+			}
+		}
+		g.drawBox(screen, g.layout.Split(sim.TableCoordinator, b), c, fmt.Sprintf("Hash Bucket %d", b))
+		for j, u := range bucket {
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableCoordinator, b, j), color.White)
+		}
+	}
+
+	// Order Machines
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if probing && j == s.ProbeIndex[i] {
+				c = color.RGBA{B: 0xff, A: 0xff} // Blue for probing
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join6{&sim.JOIN6{}} })
+}
+
+type join6 struct {
+	*sim.JOIN6
+}
+
+func (s join6) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 750, Y: 50, W: 550, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+// JOIN6 sends no packets.
+func (s join6) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return 0, 0
+}
+
+// consumedColor is the color of rows a cursor has already passed.
+var consumedColor = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
+
+func (s join6) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	if e.Step != sim.StepMerging {
+		return
+	}
+
+	// Connect the two cursors: yellow when the keys match, blue otherwise.
+	u := s.UserMachines[s.UserCursor.Split][s.UserCursor.Row]
+	o := s.OrderMachines[s.OrderCursor.Split][s.OrderCursor.Row]
+	var c color.Color = color.RGBA{B: 0xff, A: 0xff}
+	if u.UserID == o.UserID {
+		c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+	}
+	x1, y1 := g.layout.Row(sim.TableUsers, s.UserCursor.Split, s.UserCursor.Row).Anchor(layout.Right)
+	x2, y2 := g.layout.Row(sim.TableOrders, s.OrderCursor.Split, s.OrderCursor.Row).Anchor(layout.Left)
+	vector.StrokeLine(screen, x1, y1, x2, y2, 3*g.layout.Scale(), c, true)
+
+	g.drawCounter(screen, fmt.Sprintf("Comparisons: %d (every row is read once)", s.Comparisons))
+}
+
+func (s join6) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	merging := e.Step == sim.StepMerging
+	// User Machines, in UserID order
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if merging && before(sim.Cursor{Split: i, Row: j}, s.UserCursor) {
+				c = consumedColor
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Order Machines, re-keyed by UserID
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d (by %s)", e.Schema.OrderTable, i+1, e.Schema.OrderUserID))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if merging && before(sim.Cursor{Split: i, Row: j}, s.OrderCursor) {
+				c = consumedColor
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
+
+// before reports whether a comes before b.
+func before(a, b sim.Cursor) bool {
+	return a.Split < b.Split || a.Split == b.Split && a.Row < b.Row
+}
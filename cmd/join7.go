@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join7{&sim.JOIN7{}} })
+}
+
+type join7 struct {
+	*sim.JOIN7
+}
+
+func (s join7) Tables() []layout.Table {
+	return indexJoinTables(s.UserMachines, s.IndexMachines, s.OrderMachines)
+}
+
+func (s join7) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return indexJoinAnchor(l, ep, outgoing)
+}
+
+func (s join7) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	g.drawCounter(screen, fmt.Sprintf("RPCs: %d (JOIN3, one row at a time: %d)", len(e.RPCs), s.RowRPCs))
+	if e.Step == sim.StepUserToIndexResponse || e.Step == sim.StepIndexToOrderResponse {
+		for _, p := range e.Packets {
+			// Multi-key packets are drawn larger, with the number of keys.
+			if p.Active {
+				g.drawPacket(screen, p, 8)
+			}
+		}
+	}
+}
+
+// inBatch reports whether the current batch looks up split i, row j of the
+// table that at returns the positions in.
+func (s join7) inBatch(at func(sim.Lookup) []sim.Cursor, i, j int) bool {
+	for _, l := range s.Batch {
+		for _, c := range at(l) {
+			if c.Split == i && c.Row == j {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s join7) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	highlight := color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+	// User Machines
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && s.inBatch(func(l sim.Lookup) []sim.Cursor { return []sim.Cursor{l.User} }, i, j) {
+				c = highlight
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Index Machines
+	for i, machine := range s.IndexMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableIndex, i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("Index Machine %d", i+1))
+		for j, entry := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && e.Step != sim.StepUserToIndexRequest && s.inBatch(func(l sim.Lookup) []sim.Cursor { return l.Entries }, i, j) {
+				c = highlight
+			}
+			g.drawLabel(screen, g.indexLabel(entry), g.layout.Row(sim.TableIndex, i, j), c)
+		}
+	}
+
+	// Order Machines
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if e.Step == sim.StepIndexToOrderResponse && s.inBatch(func(l sim.Lookup) []sim.Cursor { return l.Orders }, i, j) {
+				c = highlight
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join8{&sim.JOIN8{}} })
+}
+
+type join8 struct {
+	*sim.JOIN8
+}
+
+// The covering index is wider than the one of JOIN3, so it takes space from
+// the Users and Orders.
+func (s join8) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 350, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableIndex, Area: layout.Rect{X: 450, Y: 50, W: 650, H: 550}, Rows: splitRows(s.IndexMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 1150, Y: 50, W: 400, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+func (s join8) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return indexJoinAnchor(l, ep, outgoing)
+}
+
+func (s join8) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	g.drawCounter(screen, fmt.Sprintf("RPCs: %d (JOIN3 without STORING: %d)", len(e.RPCs), len(e.RPCs)+s.BackJoinsSkipped))
+	if e.Step == sim.StepUserToIndexResponse {
+		for _, p := range e.Packets {
+			if p.Active {
+				g.drawPacket(screen, p, 5)
+			}
+		}
+	}
+}
+
+func (s join8) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	// User Machines
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.UserTable, i+1))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && s.CurrentUserIndex == j {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Index Machines, storing Item and Price
+	for i, machine := range s.IndexMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableIndex, i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("Index Machine %d (STORING %s, %s)", i+1, e.Schema.Item, e.Schema.Price))
+		for j, entry := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && e.Step != sim.StepUserToIndexRequest && isCurrent(s.IndexEntries, i, j) {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			label := fmt.Sprintf("%s, %s: %s, %s: %d", g.indexLabel(entry), e.Schema.Item, entry.Item, e.Schema.Price, entry.Price)
+			g.drawLabel(screen, label, g.layout.Row(sim.TableIndex, i, j), c)
+		}
+	}
+
+	// Order Machines, which the covering index makes unnecessary
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d (not read)", e.Schema.OrderTable, i+1))
+		for j, o := range machine {
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), consumedColor)
+		}
+	}
+}
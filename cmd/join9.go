@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+func init() {
+	register(func() Scenario { return join9{&sim.JOIN9{}} })
+}
+
+type join9 struct {
+	*sim.JOIN9
+}
+
+// The User and Index columns are placed as in JOIN3, inside one frame per
+// machine.
+func (s join9) Tables() []layout.Table {
+	return []layout.Table{
+		{Name: tableMachines, Area: layout.Rect{X: 40, Y: 40, W: 920, H: 570}, Rows: make([]int, len(s.UserMachines)), Gap: 30},
+		{Name: sim.TableUsers, Area: layout.Rect{X: 50, Y: 50, W: 400, H: 550}, Rows: splitRows(s.UserMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableIndex, Area: layout.Rect{X: 550, Y: 50, W: 400, H: 550}, Rows: splitRows(s.IndexMachines), Gap: 50, Header: 60, RowHeight: 30},
+		{Name: sim.TableOrders, Area: layout.Rect{X: 1050, Y: 50, W: 500, H: 550}, Rows: splitRows(s.OrderMachines), Gap: 50, Header: 60, RowHeight: 30},
+		joinedTable(650),
+	}
+}
+
+func (s join9) Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32) {
+	return indexJoinAnchor(l, ep, outgoing)
+}
+
+func (s join9) Draw(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	s.drawTables(g, screen)
+	g.drawJoinedTable(screen)
+	g.drawCounter(screen, fmt.Sprintf("RPCs: %d (JOIN3, index not interleaved: %d)", len(e.RPCs), len(e.RPCs)+s.LocalLookups))
+	if e.Step == sim.StepIndexToOrderResponse {
+		for _, p := range e.Packets {
+			if p.Active {
+				g.drawPacket(screen, p, 5)
+			}
+		}
+	}
+}
+
+func (s join9) drawTables(g *Game, screen *ebiten.Image) {
+	e := g.engine
+	for i := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(tableMachines, i), color.RGBA{R: 0x20, G: 0x50, B: 0x20, A: 0xff}, "")
+	}
+
+	// User rows
+	for i, machine := range s.UserMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableUsers, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("Machine %d: %s", i+1, e.Schema.UserTable))
+		for j, u := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && s.CurrentUserIndex == j {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.userLabel(u), g.layout.Row(sim.TableUsers, i, j), c)
+		}
+	}
+
+	// Index rows, interleaved in the User rows of the same machine
+	for i, machine := range s.IndexMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableIndex, i), color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("Machine %d: Index (interleaved)", i+1))
+		for j, entry := range machine {
+			var c color.Color = color.White
+			if e.Step != sim.StepIdle && e.Step != sim.StepUserToIndexRequest && isCurrent(s.IndexEntries, i, j) {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.indexLabel(entry), g.layout.Row(sim.TableIndex, i, j), c)
+		}
+	}
+
+	// Order Machines
+	for i, machine := range s.OrderMachines {
+		g.drawBox(screen, g.layout.Split(sim.TableOrders, i), color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, fmt.Sprintf("%s Machine %d", e.Schema.OrderTable, i+1))
+		for j, o := range machine {
+			var c color.Color = color.White
+			if e.Step == sim.StepIndexToOrderResponse && isCurrent(s.OrderRows, i, j) {
+				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
+			}
+			g.drawLabel(screen, g.orderLabel(o), g.layout.Row(sim.TableOrders, i, j), c)
+		}
+	}
+}
@@ -7,1339 +7,332 @@ import (
 	"math"
 	"math/rand"
 	"os"
-	"sort"
-	"time"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/hajimehoshi/ebiten/v2/text"
 	"github.com/hajimehoshi/ebiten/v2/vector"
-	"golang.org/x/image/font/basicfont"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
 )
 
 const (
+	// The default window size, in device-independent pixels.
 	screenWidth  = 1600
 	screenHeight = 1000
-
-	stepIdle = iota
-	stepRequesting
-	stepResponding
-	stepJoining
-	stepFinished
-
-	// JOIN1 & JOIN2 specific
-	stepScanningOrderTable
-	stepRequestingMove
-	stepRespondingMove
-
-	// JOIN3 specific
-	stepUserToIndexRequest
-	stepUserToIndexResponse
-	stepIndexToOrderRequest
-	stepIndexToOrderResponse
-
-	// GROUPBY1 specific
-	stepGroupByBottomLayer
-	stepPauseBeforeSendToMiddleLayer
-	stepSendToMiddleLayer
-	stepRespondingToMiddleLayer
-	stepPauseBeforeGroupByMiddleLayer
-	stepGroupByMiddleLayer
-	stepPauseBeforeSendToTopLayer
-	stepSendToTopLayer
-	stepRespondingToTopLayer
-	stepPauseBeforeGroupByTopLayer
-	stepGroupByTopLayer
-	stepPauseBeforeRestart
-
-	// GROUPBY2 specific
-	stepParallelAggregation
-	stepG2_PauseBeforeRestart
-
-	textScale = 24.0 / 13.0
 )
 
-type User struct {
-	UserID int
-	Name   string
-}
-
-type Order struct {
-	OrderID int
-	UserID  int
-	Item    string
-	Price   int
-}
-
-type IndexEntry struct {
-	UserID  int
-	OrderID int
-}
-
-type JoinedData struct {
-	User  User
-	Order Order
-}
-
-type AggregationResult struct {
-	Item  string
-	Price int
-}
-
+// Game runs a Scenario on a sim.Engine and renders it.
 type Game struct {
-	// Common
-	AnimationType  string
-	animationStep  int
-	showJoined     bool
-	animationTimer *time.Ticker
-	Joined         []JoinedData
-	packetSpeed    float32
-
-	// Data stores
-	Users         []User
-	Orders        []Order
-	UserMachines  [2][]User
-	OrderMachines [4][]Order // For GROUPBY1, 4 machines
-	IndexMachines [2][]IndexEntry
-
-	// GROUPBY1 specific data
-	BottomLayerResults [4][]AggregationResult
-	MiddleLayerResults [2][]AggregationResult
-	TopLayerResult     []AggregationResult
-
-	// State
-	currentUserIndex         int
-	currentUserMachineIndex  int
-	orderScanIndex           [2]int
-	matchFound               [2]bool
-	currentOrderMachineIndex [2]int
-	currentOrderIndex        [2]int
-	orderScanMachineIndex    [2]int
-	needsToMove              [2]bool
-	currentIndexMachineIndex [2]int
-	currentIndexIndex        [2]int
-	AllOrders                []Order // For GROUPBY2
-	ItemLocations            map[string][]struct {
-		Split int
-		Row   int
-	}
-	ParallelScanIndex    int
-	ParallelAggregations map[string]int
-
-	// Packets (up to 4 for GROUPBY1)
-	packetX, packetY             [4]float32
-	packetStartX, packetStartY   [4]float32
-	packetTargetX, packetTargetY [4]float32
-	packetSpeedX, packetSpeedY   [4]float32
-	packetActive                 [4]bool
+	scenario Scenario
+	engine   *sim.Engine
+	layout   *layout.Layout
+	faces    fontFaces
 }
 
 // --- Game Setup ---
 
-func NewGame(animationType string) *Game {
-	switch animationType {
-	case "JOIN2":
-		return NewGameJOIN2(animationType)
-	case "JOIN3":
-		return NewGameJOIN3(animationType)
-	case "GROUPBY1":
-		return NewGameGROUPBY1(animationType)
-	case "GROUPBY2":
-		return NewGameGROUPBY2(animationType)
-	default:
-		return NewGameJOIN1(animationType)
-	}
-}
-
-func NewGameJOIN1(animationType string) *Game {
-	users := []User{
-		{UserID: 1, Name: "Alice"}, {UserID: 2, Name: "Bob"}, {UserID: 3, Name: "Charlie"}, {UserID: 4, Name: "David"}, {UserID: 5, Name: "Eve"},
-		{UserID: 6, Name: "Frank"}, {UserID: 7, Name: "Grace"}, {UserID: 8, Name: "Heidi"}, {UserID: 9, Name: "Ivan"}, {UserID: 10, Name: "Judy"},
-	}
-	orders := make([]Order, 10)
-	userIDs := make([]int, len(users))
-	for i, u := range users {
-		orders[i] = Order{OrderID: 101 + i, Item: fmt.Sprintf("Item%d", 101+i)}
-		userIDs[i] = u.UserID
-	}
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(userIDs), func(i, j int) { userIDs[i], userIDs[j] = userIDs[j], userIDs[i] })
-	for i := range orders {
-		orders[i].UserID = userIDs[i]
-	}
-	g := &Game{
-		Users:         users,
-		Orders:        []Order{orders[0], orders[1], orders[2], orders[3], orders[4], orders[5], orders[6], orders[7], orders[8], orders[9]},
-		animationStep: stepIdle,
-		packetSpeed:   15,
-		AnimationType: animationType,
+// NewGame sets up the named scenario. When d is not nil the scenario runs on
+// it instead of generated data. Non-zero split counts in t override the
+// scenario's topology and groupBy, if not empty, its GROUP BY columns. agg is
+// the aggregate function of the GROUP BY scenarios.
+func NewGame(animationType string, rng *rand.Rand, d *sim.Dataset, t sim.Topology, groupBy []sim.Column, agg sim.Aggregate) (*Game, error) {
+	s, err := lookupScenario(animationType)
+	if err != nil {
+		return nil, err
 	}
-	return g
-}
-
-func NewGameJOIN2(animationType string) *Game {
-	userMachines := [2][]User{}
-	userMachines[0] = []User{
-		{UserID: 1, Name: "Alice"}, {UserID: 2, Name: "Bob"}, {UserID: 3, Name: "Charlie"}, {UserID: 4, Name: "David"}, {UserID: 5, Name: "Eve"},
-	}
-	userMachines[1] = []User{
-		{UserID: 6, Name: "Frank"}, {UserID: 7, Name: "Grace"}, {UserID: 8, Name: "Heidi"}, {UserID: 9, Name: "Ivan"}, {UserID: 10, Name: "Judy"},
-	}
-
-	orderMachines := [4][]Order{}
-	orderMachines[0] = make([]Order, 5)
-	orderMachines[1] = make([]Order, 5)
-	userIDs := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(userIDs), func(i, j int) { userIDs[i], userIDs[j] = userIDs[j], userIDs[i] })
-	for i := 0; i < 5; i++ {
-		orderMachines[0][i] = Order{OrderID: 101 + i, UserID: userIDs[i], Item: fmt.Sprintf("Item%d", 101+i)}
-		orderMachines[1][i] = Order{OrderID: 106 + i, UserID: userIDs[i+5], Item: fmt.Sprintf("Item%d", 106+i)}
-	}
-
-	g := &Game{
-		UserMachines:  userMachines,
-		OrderMachines: orderMachines,
-		animationStep: stepIdle,
-		packetSpeed:   10,
-		AnimationType: animationType,
-	}
-	return g
-}
-
-func NewGameJOIN3(animationType string) *Game {
-	userMachines := [2][]User{}
-	userMachines[0] = []User{
-		{UserID: 1, Name: "Alice"}, {UserID: 2, Name: "Bob"}, {UserID: 3, Name: "Charlie"}, {UserID: 4, Name: "David"}, {UserID: 5, Name: "Eve"},
-	}
-	userMachines[1] = []User{
-		{UserID: 6, Name: "Frank"}, {UserID: 7, Name: "Grace"}, {UserID: 8, Name: "Heidi"}, {UserID: 9, Name: "Ivan"}, {UserID: 10, Name: "Judy"},
-	}
-
-	orderMachines := [4][]Order{}
-	orderMachines[0] = make([]Order, 5)
-	orderMachines[1] = make([]Order, 5)
-	userIDs := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(userIDs), func(i, j int) { userIDs[i], userIDs[j] = userIDs[j], userIDs[i] })
-
-	fullOrderList := make([]Order, 0, 10)
-	for i := 0; i < 5; i++ {
-		orderMachines[0][i] = Order{OrderID: 101 + i, UserID: userIDs[i], Item: fmt.Sprintf("Item%d", 101+i)}
-		orderMachines[1][i] = Order{OrderID: 106 + i, UserID: userIDs[i+5], Item: fmt.Sprintf("Item%d", 106+i)}
-		fullOrderList = append(fullOrderList, orderMachines[0][i], orderMachines[1][i])
-	}
-
-	index := make([]IndexEntry, 10)
-	for i, o := range fullOrderList {
-		index[i] = IndexEntry{UserID: o.UserID, OrderID: o.OrderID}
-	}
-	sort.Slice(index, func(i, j int) bool { return index[i].UserID < index[j].UserID })
-
-	indexMachines := [2][]IndexEntry{}
-	indexMachines[0] = index[0:5]
-	indexMachines[1] = index[5:10]
-
-	g := &Game{
-		UserMachines:  userMachines,
-		OrderMachines: orderMachines,
-		IndexMachines: indexMachines,
-		animationStep: stepIdle,
-		packetSpeed:   15,
-		AnimationType: animationType,
-	}
-	return g
-}
-
-func NewGameGROUPBY1(animationType string) *Game {
-	rand.Seed(time.Now().UnixNano())
-	orderMachines := [4][]Order{}
-	items := []string{"Apple", "Banana", "Cherry"}
-	for i := 0; i < 4; i++ {
-		orderMachines[i] = make([]Order, 10)
-		for j := 0; j < 10; j++ {
-			orderMachines[i][j] = Order{
-				OrderID: 1000 + i*10 + j,
-				UserID:  rand.Intn(100),
-				Item:    items[rand.Intn(len(items))],
-				Price:   100 + rand.Intn(900),
-			}
+	if t != (sim.Topology{}) {
+		if d != nil && (t.Users != 0 || t.Orders != 0) {
+			return nil, fmt.Errorf("the splits of %s and %s come from the scenario file", d.Schema.UserTable, d.Schema.OrderTable)
+		}
+		ts, ok := s.(sim.TopologyScenario)
+		if !ok {
+			return nil, fmt.Errorf("%s has a fixed number of splits", animationType)
+		}
+		if err := ts.SetTopology(t); err != nil {
+			return nil, err
 		}
 	}
-
-	g := &Game{
-		OrderMachines: orderMachines,
-		animationStep: stepIdle,
-		packetSpeed:   4, // Slower speed
-		AnimationType: animationType,
-	}
-	return g
-}
-
-func NewGameGROUPBY2(animationType string) *Game {
-	rand.Seed(time.Now().UnixNano())
-	items := []string{"Apple", "Banana", "Cherry", "Grape", "Orange"}
-
-	// Create 40 orders with random items
-	allOrders := make([]Order, 40)
-	for i := 0; i < 40; i++ {
-		allOrders[i] = Order{
-			OrderID: 1000 + i,
-			UserID:  rand.Intn(100),
-			Item:    items[rand.Intn(len(items))],
-			Price:   100 + rand.Intn(900),
+	if d != nil {
+		ds, ok := s.(sim.DatasetScenario)
+		if !ok {
+			return nil, fmt.Errorf("%s cannot run on a scenario file", animationType)
+		}
+		if err := ds.UseDataset(d); err != nil {
+			return nil, err
 		}
 	}
-
-	// Sort by Item, then OrderID
-	sort.Slice(allOrders, func(i, j int) bool {
-		if allOrders[i].Item != allOrders[j].Item {
-			return allOrders[i].Item < allOrders[j].Item
+	if len(groupBy) > 0 {
+		gs, ok := s.(sim.GroupByScenario)
+		if !ok {
+			return nil, fmt.Errorf("%s has no GROUP BY", animationType)
 		}
-		return allOrders[i].OrderID < allOrders[j].OrderID
-	})
-
-	// Distribute sorted orders into 4 machines
-	orderMachines := [4][]Order{}
-	for i := 0; i < 4; i++ {
-		orderMachines[i] = make([]Order, 10)
-		for j := 0; j < 10; j++ {
-			orderMachines[i][j] = allOrders[i*10+j]
+		if err := gs.SetGroupBy(groupBy); err != nil {
+			return nil, err
 		}
 	}
-
-	g := &Game{
-		OrderMachines: orderMachines,
-		AllOrders:     allOrders,
-		animationStep: stepIdle,
-		packetSpeed:   4,
-		AnimationType: animationType,
+	if as, ok := s.(sim.AggregateScenario); ok {
+		if err := as.SetAggregate(agg); err != nil {
+			return nil, err
+		}
+	} else if agg != sim.AggSum {
+		return nil, fmt.Errorf("%s has no aggregate function", animationType)
 	}
-	return g
+	g := &Game{scenario: s, engine: sim.NewEngine(s, rng), faces: fontFaces{}}
+	g.layout = layout.New(screenWidth, screenHeight, s.Tables()...)
+	return g, nil
 }
 
-// --- Core Logic ---
-
-func (g *Game) startAnimation() {
-	if g.AnimationType == "JOIN3" {
-		g.animationStep = stepUserToIndexRequest
-	} else if g.AnimationType == "GROUPBY1" {
-		g.animationStep = stepGroupByBottomLayer
-		g.BottomLayerResults = [4][]AggregationResult{}
-		g.MiddleLayerResults = [2][]AggregationResult{}
-		g.TopLayerResult = []AggregationResult{}
-	} else if g.AnimationType == "GROUPBY2" {
-		g.animationStep = stepParallelAggregation // New step name
-		g.ItemLocations = make(map[string][]struct {
-			Split int
-			Row   int
-		})
-		for i, machine := range g.OrderMachines {
-			for j, order := range machine {
-				g.ItemLocations[order.Item] = append(g.ItemLocations[order.Item], struct {
-					Split int
-					Row   int
-				}{i, j})
-			}
+func (g *Game) Update() error {
+	if g.engine.Step == sim.StepIdle && !g.engine.AutoStart {
+		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+			g.engine.Start()
 		}
-		g.ParallelScanIndex = 0
-		g.ParallelAggregations = make(map[string]int)
-		g.TopLayerResult = []AggregationResult{}
-	} else {
-		g.animationStep = stepRequesting
 	}
-	g.currentUserIndex = 0
-	g.currentUserMachineIndex = 0
-	g.Joined = []JoinedData{}
-	g.animationTimer = time.NewTicker(200 * time.Millisecond)
-	g.setPacketStartPosition()
+	g.updateControls()
+	g.engine.Tick()
+	return nil
 }
 
-func (g *Game) Update() error {
-	switch g.AnimationType {
-	case "JOIN2":
-		return g.updateJOIN2()
-	case "JOIN3":
-		return g.updateJOIN3()
-	case "GROUPBY1":
-		return g.updateGROUPBY1()
-	case "GROUPBY2":
-		return g.updateGROUPBY2()
-	default: // JOIN1 and empty
-		return g.updateJOIN1()
+// updateControls handles pausing (P) and speed changes (Up/Down).
+func (g *Game) updateControls() {
+	scheduler := g.engine.Scheduler
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		if scheduler.Paused() {
+			scheduler.Resume()
+		} else {
+			scheduler.Pause()
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) {
+		scheduler.SetSpeed(scheduler.Speed() * 2)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) {
+		scheduler.SetSpeed(scheduler.Speed() / 2)
 	}
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	switch g.AnimationType {
-	case "JOIN2":
-		g.drawJOIN2(screen)
-	case "JOIN3":
-		g.drawJOIN3(screen)
-	case "GROUPBY1":
-		g.drawGROUPBY1(screen)
-	case "GROUPBY2":
-		g.drawGROUPBY2(screen)
-	default: // JOIN1 and empty
-		g.drawJOIN1(screen)
-	}
+	g.scenario.Draw(g, screen)
+	g.drawStatus(screen)
 }
 
+// Layout renders at the device's resolution and lays the scenario out again
+// whenever the window size or scale factor changes.
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+	scale := ebiten.Monitor().DeviceScaleFactor()
+	w := int(math.Ceil(float64(outsideWidth) * scale))
+	h := int(math.Ceil(float64(outsideHeight) * scale))
+	if float32(w) != g.layout.Width || float32(h) != g.layout.Height {
+		g.layout = layout.New(float32(w), float32(h), g.scenario.Tables()...)
+	}
+	return w, h
 }
 
 func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Spanner Distributed JOIN Animation")
-	animationType := ""
-	if len(os.Args) > 1 {
-		animationType = os.Args[1]
-	}
-	if err := ebiten.RunGame(NewGame(animationType)); err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 }
 
-// --- Update Logic ---
-
-func (g *Game) updateJOIN1() error {
-	if g.animationStep == stepIdle {
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-				g.startAnimation()
-			}
-		}
-	}
-	switch g.animationStep {
-	case stepRequesting:
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			g.packetTargetX[0] = 560
-			g.packetTargetY[0] = 110 + 12
-			g.setupPacket(0)
-			g.animationStep = stepResponding
-		}
-	case stepResponding:
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			if g.movePacket(0) {
-				g.animationStep = stepScanningOrderTable
-				g.orderScanIndex[0] = 0
-				g.packetY[0] = 110 + float32(g.orderScanIndex[0]*30) + 12
-			}
-		}
-	case stepScanningOrderTable:
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			select {
-			case <-g.animationTimer.C:
-				currentUser := g.Users[g.currentUserIndex]
-				if g.orderScanIndex[0] < len(g.Orders) {
-					if g.Orders[g.orderScanIndex[0]].UserID == currentUser.UserID {
-						g.animationStep = stepJoining
-						return nil
-					}
-					g.orderScanIndex[0]++
-					if g.orderScanIndex[0] < len(g.Orders) {
-						g.packetY[0] = 110 + float32(g.orderScanIndex[0]*30) + 12
-					} else {
-						g.animationStep = stepJoining // Not found
-					}
-				} else {
-					g.animationStep = stepJoining // Not found
-				}
-			default:
-			}
-		}
-	case stepJoining:
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			g.showJoined = true
-			found := false
-			for i, o := range g.Orders {
-				if o.UserID == g.Users[g.currentUserIndex].UserID {
-					g.Joined = append(g.Joined, JoinedData{User: g.Users[g.currentUserIndex], Order: o})
-					g.orderScanIndex[0] = i
-					found = true
-					break
-				}
-			}
-			if !found {
-				g.orderScanIndex[0] = -1 // Indicate not found
-			}
-			time.AfterFunc(300*time.Millisecond, func() {
-				g.currentUserIndex++
-				if g.currentUserIndex >= len(g.Users) {
-					g.animationStep = stepFinished
-				} else {
-					g.animationStep = stepRequesting
-					g.setPacketStartPosition()
-				}
-			})
-			g.animationStep = -1 // Pause while timer runs
-		}
+// --- Draw Logic ---
 
-	case stepFinished:
-		if g.AnimationType == "JOIN1" || g.AnimationType == "" {
-			g.startAnimation()
-		}
+// drawStatus shows the playback speed and whether the animation is paused.
+func (g *Game) drawStatus(screen *ebiten.Image) {
+	scheduler := g.engine.Scheduler
+	status := fmt.Sprintf("x%g", scheduler.Speed())
+	if scheduler.Paused() {
+		status += " PAUSED"
 	}
-	return nil
+	x, y := g.layout.Point(layout.DesignWidth-200, 10)
+	g.drawText(screen, status, x, y, color.White)
 }
 
-func (g *Game) updateJOIN2() error {
-	if g.animationStep == stepIdle {
-		if g.AnimationType == "JOIN2" {
-			g.startAnimation()
-		}
-	}
-	switch g.animationStep {
-	case stepRequesting:
-		if g.AnimationType == "JOIN2" {
-			for i := 0; i < 2; i++ {
-				g.packetTargetX[i] = 760
-				// Both users start searching from OrderMachine 0
-				g.packetTargetY[i] = float32(50 + 60 + 12)
-				g.setupPacket(i)
-			}
-			g.animationStep = stepResponding
-		}
-	case stepResponding:
-		if g.AnimationType == "JOIN2" {
-			packetsFinished := 0
-			for i := 0; i < 2; i++ {
-				if g.movePacket(i) {
-					packetsFinished++
-				}
-			}
-			if packetsFinished == 2 {
-				g.animationStep = stepScanningOrderTable
-				g.orderScanIndex = [2]int{0, 0}
-				g.matchFound = [2]bool{false, false}
-				g.orderScanMachineIndex = [2]int{0, 0} // Both start at machine 0
-				g.needsToMove = [2]bool{false, false}
-				for i := 0; i < 2; i++ {
-					// Both start at machine 0
-					g.packetY[i] = float32(50 + 60 + 12)
-				}
-			}
-		}
-	case stepScanningOrderTable:
-		if g.AnimationType == "JOIN2" {
-			select {
-			case <-g.animationTimer.C:
-				var needsToMove bool
-				for i := 0; i < 2; i++ {
-					if g.matchFound[i] || g.needsToMove[i] {
-						continue
-					}
-
-					currentUser := g.UserMachines[i][g.currentUserIndex]
-					scanningMachine := g.orderScanMachineIndex[i]
-
-					if g.orderScanIndex[i] < len(g.OrderMachines[scanningMachine]) {
-						if g.OrderMachines[scanningMachine][g.orderScanIndex[i]].UserID == currentUser.UserID {
-							g.matchFound[i] = true
-							g.currentOrderMachineIndex[i] = scanningMachine
-							g.currentOrderIndex[i] = g.orderScanIndex[i]
-						} else {
-							g.orderScanIndex[i]++
-							if g.orderScanIndex[i] < len(g.OrderMachines[scanningMachine]) {
-								g.packetY[i] = float32(50+60+g.orderScanIndex[i]*30+12) + float32(scanningMachine*300)
-							}
-						}
-					}
-
-					if !g.matchFound[i] && g.orderScanIndex[i] >= len(g.OrderMachines[scanningMachine]) {
-						if scanningMachine == 0 { // Finished scanning machine 0, move to 1
-							g.needsToMove[i] = true
-							needsToMove = true
-						} else { // Finished scanning machine 1
-							g.matchFound[i] = true // Not found in either machine.
-							g.currentOrderIndex[i] = -1
-						}
-					}
-				}
-
-				if needsToMove {
-					g.animationStep = stepRequestingMove
-				} else if g.matchFound[0] && g.matchFound[1] {
-					g.animationStep = stepJoining
-				}
-			default:
-			}
-		}
-	case stepRequestingMove:
-		if g.AnimationType == "JOIN2" {
-			for i := 0; i < 2; i++ {
-				if g.needsToMove[i] {
-					// Reset start position to user table
-					userTableYOffset := float32(50 + i*300)
-					userY := userTableYOffset + 60 + float32(g.currentUserIndex*30) + 12
-					g.packetStartX[i] = 450
-					g.packetStartY[i] = userY
-					g.packetX[i], g.packetY[i] = g.packetStartX[i], g.packetStartY[i]
-
-					// Set target to OrderMachine 1
-					g.packetTargetX[i] = 760
-					g.packetTargetY[i] = float32(50+60+12) + float32(1*300)
-					g.setupPacket(i)
-				}
-			}
-			g.animationStep = stepRespondingMove
-		}
-
-	case stepRespondingMove:
-		if g.AnimationType == "JOIN2" {
-			packetsFinished := 0
-			for i := 0; i < 2; i++ {
-				if !g.needsToMove[i] {
-					packetsFinished++
-					continue
-				}
-				if g.movePacket(i) {
-					g.needsToMove[i] = false
-					g.orderScanMachineIndex[i] = 1 // Now scanning machine 1
-					g.orderScanIndex[i] = 0
-					g.packetY[i] = float32(50+60+g.orderScanIndex[i]*30+12) + float32(1*300)
-					packetsFinished++
-				}
-			}
-			if packetsFinished == 2 {
-				g.animationStep = stepScanningOrderTable
-			}
-		}
-	case stepJoining:
-		if g.AnimationType == "JOIN2" {
-			g.showJoined = true
-			for i := 0; i < 2; i++ {
-				if g.matchFound[i] && g.currentOrderIndex[i] != -1 {
-					currentUser := g.UserMachines[i][g.currentUserIndex]
-					order := g.OrderMachines[g.currentOrderMachineIndex[i]][g.currentOrderIndex[i]]
-					g.Joined = append(g.Joined, JoinedData{User: currentUser, Order: order})
-				}
-			}
-			time.AfterFunc(300*time.Millisecond, func() {
-				g.currentUserIndex++
-				if g.currentUserIndex >= len(g.UserMachines[0]) {
-					g.animationStep = stepFinished
-				} else {
-					g.animationStep = stepRequesting
-					g.setPacketStartPosition()
-				}
-			})
-			g.animationStep = -1 // Pause
-		}
-	case stepFinished:
-		if g.AnimationType == "JOIN2" {
-			g.startAnimation()
-		}
-	}
-	return nil
+// drawCounter shows a running count, such as the number of RPCs, in the top
+// left corner.
+func (g *Game) drawCounter(screen *ebiten.Image, str string) {
+	x, y := g.layout.Point(50, 10)
+	g.drawText(screen, str, x, y, color.White)
 }
 
-func (g *Game) updateGROUPBY1() error {
-	if g.animationStep == stepIdle {
-		if g.AnimationType == "GROUPBY1" {
-			if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-				g.startAnimation()
-			}
-		}
-	}
-	switch g.animationStep {
-	case stepGroupByBottomLayer:
-		for i := 0; i < 4; i++ {
-			result := make(map[string]int)
-			for _, order := range g.OrderMachines[i] {
-				result[order.Item] += order.Price
-			}
-			var agg []AggregationResult
-			for item, price := range result {
-				agg = append(agg, AggregationResult{Item: item, Price: price})
-			}
-			sort.Slice(agg, func(i, j int) bool { return agg[i].Item < agg[j].Item })
-			g.BottomLayerResults[i] = agg
-		}
-		g.animationStep = stepPauseBeforeSendToMiddleLayer
-		time.AfterFunc(2*time.Second, func() {
-			g.animationStep = stepSendToMiddleLayer
-		})
-	case stepPauseBeforeSendToMiddleLayer:
-		// Wait for timer
-	case stepSendToMiddleLayer:
-		// Setup packets from bottom to middle
-		for i := 0; i < 4; i++ {
-			g.packetActive[i] = true
-			g.packetStartX[i] = float32(50 + i*400 + 175) // Adjusted for 4 machines
-			g.packetStartY[i] = 650                       // Bottom layer y
-			// Route to middle layer machines
-			if i < 2 {
-				g.packetTargetX[i] = 400
-			} else {
-				g.packetTargetX[i] = 1200
-			}
-			g.packetTargetY[i] = 525                                          // Middle layer y
-			g.packetX[i], g.packetY[i] = g.packetStartX[i], g.packetStartY[i] // Set initial position
-			g.setupPacket(i)
-		}
-		g.animationStep = stepRespondingToMiddleLayer
-	case stepRespondingToMiddleLayer:
-		packetsFinished := 0
-		for i := 0; i < 4; i++ {
-			if g.packetActive[i] {
-				if g.movePacket(i) {
-					g.packetActive[i] = false
-					packetsFinished++
-				}
-			} else {
-				packetsFinished++
-			}
-		}
-		if packetsFinished == 4 {
-			g.animationStep = stepPauseBeforeGroupByMiddleLayer
-			time.AfterFunc(1*time.Second, func() {
-				g.animationStep = stepGroupByMiddleLayer
-			})
-		}
-	case stepPauseBeforeGroupByMiddleLayer:
-		// Wait for timer
-	case stepGroupByMiddleLayer:
-		// Merge results in middle layer
-		result0 := make(map[string]int)
-		for i := 0; i < 2; i++ {
-			for _, res := range g.BottomLayerResults[i] {
-				result0[res.Item] += res.Price
-			}
-		}
-		var agg0 []AggregationResult
-		for item, price := range result0 {
-			agg0 = append(agg0, AggregationResult{Item: item, Price: price})
-		}
-		sort.Slice(agg0, func(i, j int) bool { return agg0[i].Item < agg0[j].Item })
-		g.MiddleLayerResults[0] = agg0
-
-		result1 := make(map[string]int)
-		for i := 2; i < 4; i++ {
-			for _, res := range g.BottomLayerResults[i] {
-				result1[res.Item] += res.Price
-			}
-		}
-		var agg1 []AggregationResult
-		for item, price := range result1 {
-			agg1 = append(agg1, AggregationResult{Item: item, Price: price})
-		}
-		sort.Slice(agg1, func(i, j int) bool { return agg1[i].Item < agg1[j].Item })
-		g.MiddleLayerResults[1] = agg1
-
-		g.animationStep = stepPauseBeforeSendToTopLayer
-		time.AfterFunc(2*time.Second, func() {
-			g.animationStep = stepSendToTopLayer
-		})
-	case stepPauseBeforeSendToTopLayer:
-		// Wait for timer
-	case stepSendToTopLayer:
-		// Setup packets from middle to top
-		for i := 0; i < 2; i++ {
-			g.packetActive[i] = true
-			if i == 0 {
-				g.packetStartX[i] = 400
-			} else {
-				g.packetStartX[i] = 1200
-			}
-			g.packetStartY[i] = 450                                           // Middle layer y
-			g.packetTargetX[i] = 800                                          // Top layer x
-			g.packetTargetY[i] = 275                                          // Top layer y
-			g.packetX[i], g.packetY[i] = g.packetStartX[i], g.packetStartY[i] // Set initial position
-			g.setupPacket(i)
-		}
-		for i := 2; i < 4; i++ {
-			g.packetActive[i] = false
-		}
-		g.animationStep = stepRespondingToTopLayer
-	case stepRespondingToTopLayer:
-		packetsFinished := 0
-		for i := 0; i < 2; i++ {
-			if g.packetActive[i] {
-				if g.movePacket(i) {
-					g.packetActive[i] = false
-					packetsFinished++
-				}
-			} else {
-				packetsFinished++
-			}
-		}
-		if packetsFinished == 2 {
-			g.animationStep = stepPauseBeforeGroupByTopLayer
-			time.AfterFunc(1*time.Second, func() {
-				g.animationStep = stepGroupByTopLayer
-			})
-		}
-	case stepPauseBeforeGroupByTopLayer:
-		// Wait for timer
-	case stepGroupByTopLayer:
-		// Merge results in top layer
-		result := make(map[string]int)
-		for _, res := range g.MiddleLayerResults[0] {
-			result[res.Item] += res.Price
-		}
-		for _, res := range g.MiddleLayerResults[1] {
-			result[res.Item] += res.Price
-		}
-		var agg []AggregationResult
-		for item, price := range result {
-			agg = append(agg, AggregationResult{Item: item, Price: price})
-		}
-		sort.Slice(agg, func(i, j int) bool { return agg[i].Item < agg[j].Item })
-		g.TopLayerResult = agg
-		g.animationStep = stepFinished
-	case stepFinished:
-		g.animationStep = stepPauseBeforeRestart
-		time.AfterFunc(3*time.Second, func() {
-			g.startAnimation()
-		})
-	case stepPauseBeforeRestart:
-		// Wait for timer
-	}
-	return nil
+// drawStartHint asks for Space at x on the design canvas, near the bottom.
+func (g *Game) drawStartHint(screen *ebiten.Image, x float32) {
+	x, y := g.layout.Point(x, layout.DesignHeight-40)
+	g.drawText(screen, "Press Space to Start Animation", x, y, color.White)
 }
 
-func (g *Game) updateGROUPBY2() error {
-	if g.animationStep == stepIdle {
-		if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-			g.startAnimation()
-		}
-		return nil
+// drawPacket draws p as a circle of the given radius on the design canvas,
+// red unless it has a color, with its payload next to it.
+func (g *Game) drawPacket(screen *ebiten.Image, p sim.Packet, radius float32) {
+	x, y := g.packetPosition(p)
+	var clr color.Color = color.RGBA{R: 0xff, A: 0xff}
+	if p.Color != nil {
+		clr = p.Color
 	}
-
-	if g.animationStep == stepParallelAggregation {
-		select {
-		case <-g.animationTimer.C:
-			// Update aggregations for all items at the current scan index
-			itemsFinished := 0
-			for item, locations := range g.ItemLocations {
-				if g.ParallelScanIndex < len(locations) {
-					loc := locations[g.ParallelScanIndex]
-					order := g.OrderMachines[loc.Split][loc.Row]
-					g.ParallelAggregations[item] += order.Price
-				} else {
-					itemsFinished++
-				}
-			}
-
-			g.ParallelScanIndex++
-
-			// Check if all items are done
-			if itemsFinished == len(g.ItemLocations) {
-				// Transfer final results to TopLayerResult for display
-				var agg []AggregationResult
-				for item, price := range g.ParallelAggregations {
-					agg = append(agg, AggregationResult{Item: item, Price: price})
-				}
-				sort.Slice(agg, func(i, j int) bool { return agg[i].Item < agg[j].Item })
-				g.TopLayerResult = agg
-				g.animationStep = stepG2_PauseBeforeRestart
-			}
-
-		default:
-		}
-	}
-
-	if g.animationStep == stepG2_PauseBeforeRestart {
-		time.AfterFunc(3*time.Second, func() {
-			g.startAnimation()
-		})
-		// Use a different step to pause, to avoid re-triggering the timer
-		g.animationStep = stepFinished
+	vector.DrawFilledCircle(screen, x, y, radius*g.layout.Scale(), clr, false)
+	if p.Payload != "" {
+		pad := labelPadding * g.layout.Scale()
+		g.drawText(screen, p.Payload, x+pad, y+pad, color.RGBA{R: 0xff, G: 0x80, B: 0x80, A: 0xff})
 	}
-
-	return nil
 }
 
-func (g *Game) updateJOIN3() error {
-	if g.animationStep == stepIdle {
-		if g.AnimationType == "JOIN3" {
-			g.startAnimation()
-		}
-	}
-
-	switch g.animationStep {
-	case stepUserToIndexRequest:
-		if g.AnimationType == "JOIN3" {
-			for i := 0; i < 2; i++ {
-				currentUser := g.UserMachines[i][g.currentUserIndex]
-				found := false
-				for j, machine := range g.IndexMachines {
-					for k, entry := range machine {
-						if entry.UserID == currentUser.UserID {
-							g.currentIndexMachineIndex[i] = j
-							g.currentIndexIndex[i] = k
-							found = true
-							break
-						}
-					}
-					if found {
-						break
-					}
-				}
-				indexY := float32(50 + 60 + g.currentIndexIndex[i]*30 + 12)
-				if g.currentIndexMachineIndex[i] == 1 {
-					indexY += 300
-				}
-				g.packetTargetX[i] = 550
-				g.packetTargetY[i] = indexY
-				g.setupPacket(i)
-			}
-			g.animationStep = stepUserToIndexResponse
-		}
-
-	case stepUserToIndexResponse:
-		if g.AnimationType == "JOIN3" {
-			packetsFinished := 0
-			for i := 0; i < 2; i++ {
-				if g.movePacket(i) {
-					packetsFinished++
-				}
-			}
-			if packetsFinished == 2 {
-				g.animationStep = stepIndexToOrderRequest
-			}
-		}
-
-	case stepIndexToOrderRequest:
-		if g.AnimationType == "JOIN3" {
-			for i := 0; i < 2; i++ {
-				indexEntry := g.IndexMachines[g.currentIndexMachineIndex[i]][g.currentIndexIndex[i]]
-				found := false
-				for j, machine := range g.OrderMachines {
-					for k, order := range machine {
-						if order.OrderID == indexEntry.OrderID {
-							g.currentOrderMachineIndex[i] = j
-							g.currentOrderIndex[i] = k
-							found = true
-							break
-						}
-					}
-					if found {
-						break
-					}
-				}
-
-				indexY := float32(50 + 60 + g.currentIndexIndex[i]*30 + 12)
-				if g.currentIndexMachineIndex[i] == 1 {
-					indexY += 300
-				}
-				g.packetStartX[i] = 950
-				g.packetStartY[i] = indexY
-				g.packetX[i], g.packetY[i] = g.packetStartX[i], g.packetStartY[i]
-
-				orderY := float32(50 + 60 + g.currentOrderIndex[i]*30 + 12)
-				if g.currentOrderMachineIndex[i] == 1 {
-					orderY += 300
-				}
-				g.packetTargetX[i] = 1050
-				g.packetTargetY[i] = orderY
-				g.setupPacket(i)
-			}
-			g.animationStep = stepIndexToOrderResponse
-		}
-
-	case stepIndexToOrderResponse:
-		if g.AnimationType == "JOIN3" {
-			packetsFinished := 0
-			for i := 0; i < 2; i++ {
-				if g.movePacket(i) {
-					packetsFinished++
-				}
-			}
-			if packetsFinished == 2 {
-				for i := 0; i < 2; i++ {
-					user := g.UserMachines[i][g.currentUserIndex]
-					order := g.OrderMachines[g.currentOrderMachineIndex[i]][g.currentOrderIndex[i]]
-					g.Joined = append(g.Joined, JoinedData{User: user, Order: order})
-				}
-				g.showJoined = true
-				g.animationStep = stepJoining
-			}
-		}
-
-	case stepJoining:
-		if g.AnimationType == "JOIN3" {
-			select {
-			case <-g.animationTimer.C:
-				g.currentUserIndex++
-				if g.currentUserIndex >= len(g.UserMachines[0]) {
-					g.animationStep = stepFinished
-				} else {
-					g.animationStep = stepUserToIndexRequest
-					g.setPacketStartPosition()
-				}
-			default:
-			}
-		}
+// tableJoined is the layout table of the JOIN result.
+const tableJoined = "Joined"
 
-	case stepFinished:
-		if g.AnimationType == "JOIN3" {
-			g.startAnimation()
-		}
-	}
-	return nil
+// joinedTable lays out the JOIN result below the tables, starting at y.
+func joinedTable(y float32) layout.Table {
+	return layout.Table{Name: tableJoined, Area: layout.Rect{X: 50, Y: y, W: 1500, H: 300}, Header: 60, RowHeight: 30}
 }
 
-// --- Draw Logic ---
-
-func (g *Game) drawJOIN1(screen *ebiten.Image) {
-	g.drawTablesJOIN1(screen)
-	g.drawJoinedTable(screen, 520)
-	if g.animationStep == stepResponding || g.animationStep == stepScanningOrderTable {
-		vector.DrawFilledCircle(screen, g.packetX[0], g.packetY[0], 5, color.RGBA{R: 0xff, A: 0xff}, false)
+// drawJoinedTable shows the JOIN result in two columns, or more once users
+// with many orders fill them.
+func (g *Game) drawJoinedTable(screen *ebiten.Image) {
+	e := g.engine
+	if !e.ShowJoined {
+		return
 	}
-	if g.animationStep == stepIdle {
-		g.drawScaledText(screen, "Press Space to Start Animation", 393, screenHeight-40, color.White)
+	r := g.layout.Split(tableJoined, 0)
+	g.drawBox(screen, r, color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, fmt.Sprintf("%s JOIN Result", e.JoinType))
+	first := g.layout.Row(tableJoined, 0, 0)
+	perColumn := max(int((r.Y+r.H-first.Y)/first.H), 1)
+	columns := max(2, (len(e.Joined)+perColumn-1)/perColumn)
+	for i, j := range e.Joined {
+		row := g.layout.Row(tableJoined, 0, i/columns)
+		row.X += float32(i%columns) * r.W / float32(columns)
+		g.drawLabel(screen, g.joinedLabel(j), row, color.White)
 	}
 }
 
-func (g *Game) drawJOIN2(screen *ebiten.Image) {
-	g.drawTablesJOIN2(screen)
-	g.drawJoinedTable(screen, 650)
-	if g.animationStep == stepResponding || g.animationStep == stepScanningOrderTable || g.animationStep == stepRespondingMove {
-		for i := 0; i < 2; i++ {
-			vector.DrawFilledCircle(screen, g.packetX[i], g.packetY[i], 5, color.RGBA{R: 0xff, A: 0xff}, false)
-		}
+// splitRows returns the number of rows of every split.
+func splitRows[T any](splits [][]T) []int {
+	rows := make([]int, len(splits))
+	for i, split := range splits {
+		rows[i] = len(split)
 	}
+	return rows
 }
 
-func (g *Game) drawGROUPBY1(screen *ebiten.Image) {
-	// Bottom Layer (4 machines)
-	for i := 0; i < 4; i++ {
-		x := float32(50 + i*400)
-		vector.DrawFilledRect(screen, x, 650, 350, 300, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Split %d", i+1), int(x)+10, 660, color.White)
-		if g.animationStep <= stepGroupByBottomLayer {
-			g.drawScaledText(screen, "OrderID,UserID,Item,Price", int(x)+10, 690, color.White)
-			for j, order := range g.OrderMachines[i] {
-				g.drawScaledText(screen, fmt.Sprintf("%d,%d,%s,%d", order.OrderID, order.UserID, order.Item, order.Price), int(x)+10, 715+j*25, color.White)
-			}
-		} else {
-			for j, res := range g.BottomLayerResults[i] {
-				g.drawScaledText(screen, fmt.Sprintf("%s: %d", res.Item, res.Price), int(x)+10, 690+j*25, color.RGBA{R: 0xff, G: 0xff, A: 0xff})
-			}
-		}
-	}
-
-	// Middle Layer (2 machines)
-	for i := 0; i < 2; i++ {
-		x := float32(200 + i*800)
-		vector.DrawFilledRect(screen, x, 450, 400, 150, color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Mid-Tier %d", i+1), int(x)+10, 460, color.White)
-		if g.animationStep >= stepGroupByMiddleLayer {
-			for j, res := range g.MiddleLayerResults[i] {
-				g.drawScaledText(screen, fmt.Sprintf("%s: %d", res.Item, res.Price), int(x)+10, 490+j*25, color.White)
-			}
-		}
-	}
-
-	// Top Layer (1 machine)
-	vector.DrawFilledRect(screen, 600, 150, 400, 250, color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, false)
-	g.drawScaledText(screen, "Top-Tier", 610, 160, color.White)
-	if g.animationStep >= stepGroupByTopLayer {
-		for i, res := range g.TopLayerResult {
-			g.drawScaledText(screen, fmt.Sprintf("%s: %d", res.Item, res.Price), 610, 190+i*25, color.White)
-		}
-	}
+// labelPadding is the space between the edge of a box and its text on the
+// design canvas.
+const labelPadding = 10
 
-	// Packets
-	for i := 0; i < 4; i++ {
-		if g.packetActive[i] {
-			vector.DrawFilledCircle(screen, g.packetX[i], g.packetY[i], 10, color.RGBA{R: 0xff, A: 0xff}, false)
-		}
-	}
-
-	if g.animationStep == stepIdle {
-		g.drawScaledText(screen, "Press Space to Start Animation", 594, screenHeight-40, color.White)
-	}
+// drawBox fills r and writes title in its top left corner.
+func (g *Game) drawBox(screen *ebiten.Image, r layout.Rect, clr color.Color, title string) {
+	vector.DrawFilledRect(screen, r.X, r.Y, r.W, r.H, clr, false)
+	pad := labelPadding * g.layout.Scale()
+	g.drawText(screen, title, r.X+pad, r.Y+pad, color.White)
 }
 
-func (g *Game) drawGROUPBY2(screen *ebiten.Image) {
-	// Left side: 4 splits
-	for i := 0; i < 4; i++ {
-		x := float32(50 + i*225) // Tighter packing
-		vector.DrawFilledRect(screen, x, 50, 200, 900, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Split %d", i+1), int(x)+10, 60, color.White)
-		g.drawScaledText(screen, "Item,OrderID,Price", int(x)+10, 90, color.White)
-		for j, order := range g.OrderMachines[i] {
-			g.drawScaledText(screen, fmt.Sprintf("%s,%d,%d", order.Item, order.OrderID, order.Price), int(x)+10, 115+j*25, color.White)
-		}
-	}
-
-	// Right side: Final Result
-	vector.DrawFilledRect(screen, 1000, 50, 550, 900, color.RGBA{R: 0x60, G: 0x30, B: 0x30, A: 0xff}, false)
-	g.drawScaledText(screen, "Final Aggregation Result", 1010, 60, color.White)
-
-	// Draw highlights and results
-	if g.animationStep == stepParallelAggregation {
-		// Draw highlights
-		for _, locations := range g.ItemLocations {
-			if g.ParallelScanIndex < len(locations) {
-				loc := locations[g.ParallelScanIndex]
-				x := float32(50 + loc.Split*225)
-				y := float32(115 + loc.Row*25)
-				vector.DrawFilledRect(screen, x, y-13, 200, 25, color.RGBA{R: 0xff, G: 0xff, A: 0x80}, false)
-			}
-		}
-		// Draw running totals
-		var items []string
-		for item := range g.ParallelAggregations {
-			items = append(items, item)
-		}
-		sort.Strings(items)
-		yOffset := 115
-		for _, item := range items {
-			price := g.ParallelAggregations[item]
-			g.drawScaledText(screen, fmt.Sprintf("%s: %d (Processing...)", item, price), 1010, yOffset, color.White)
-			yOffset += 25
-		}
+// drawLabel writes str at the start of row r.
+func (g *Game) drawLabel(screen *ebiten.Image, str string, r layout.Rect, clr color.Color) {
+	g.drawText(screen, str, r.X+labelPadding*g.layout.Scale(), r.Y, clr)
+}
 
-	} else if g.animationStep == stepFinished || g.animationStep == stepG2_PauseBeforeRestart {
-		// Draw final results
-		yOffset := 115
-		for _, res := range g.TopLayerResult {
-			g.drawScaledText(screen, fmt.Sprintf("%s: %d", res.Item, res.Price), 1010, yOffset, color.White)
-			yOffset += 25
-		}
-	}
+// --- Helpers ---
 
-	if g.animationStep == stepIdle {
-		g.drawScaledText(screen, "Press Space to Start Animation", 594, screenHeight-40, color.White)
-	}
+func (g *Game) userLabel(u sim.User) string {
+	schema := g.engine.Schema
+	return fmt.Sprintf("%s: %d, %s: %s", schema.UserID, u.UserID, schema.UserName, u.Name)
 }
 
-func (g *Game) drawJOIN3(screen *ebiten.Image) {
-	g.drawTablesJOIN3(screen)
-	g.drawJoinedTable(screen, 650)
-	if g.animationStep == stepUserToIndexResponse || g.animationStep == stepIndexToOrderResponse {
-		for i := 0; i < 2; i++ {
-			vector.DrawFilledCircle(screen, g.packetX[i], g.packetY[i], 5, color.RGBA{R: 0xff, A: 0xff}, false)
-		}
-	}
+func (g *Game) orderLabel(o sim.Order) string {
+	schema := g.engine.Schema
+	return fmt.Sprintf("%s: %d, %s: %d, %s: %s", schema.OrderID, o.OrderID, schema.OrderUserID, o.UserID, schema.Item, o.Item)
 }
 
-func (g *Game) drawTablesJOIN1(screen *ebiten.Image) {
-	// User Table
-	vector.DrawFilledRect(screen, 50, 50, 400, 450, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-	g.drawScaledText(screen, "User Table", 60, 60, color.White)
-	for i, u := range g.Users {
-		var c color.Color = color.White
-		if g.animationStep > stepIdle && g.currentUserIndex == i {
-			c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
-		}
-		g.drawScaledText(screen, fmt.Sprintf("UserID: %d, Name: %s", u.UserID, u.Name), 60, 110+i*30, c)
-	}
-
-	// Order Table
-	vector.DrawFilledRect(screen, 550, 50, 500, 450, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-	g.drawScaledText(screen, "Order Table", 560, 60, color.White)
-	for i, o := range g.Orders {
-		var c color.Color = color.White
-		if g.animationStep == stepScanningOrderTable {
-			if i == g.orderScanIndex[0] {
-				c = color.RGBA{B: 0xff, A: 0xff} // Blue for scanning
-			}
-		} else if g.animationStep == stepJoining {
-			if i == g.orderScanIndex[0] {
-				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff} // Yellow for found
-			}
-		}
-		g.drawScaledText(screen, fmt.Sprintf("OrderID: %d, UserID: %d, Item: %s", o.OrderID, o.UserID, o.Item), 560, 110+i*30, c)
-	}
+func (g *Game) indexLabel(entry sim.IndexEntry) string {
+	schema := g.engine.Schema
+	return fmt.Sprintf("%s: %d, %s: %d", schema.OrderUserID, entry.UserID, schema.OrderID, entry.OrderID)
 }
 
-func (g *Game) drawTablesJOIN2(screen *ebiten.Image) {
-	// User Machines
-	for i := 0; i < 2; i++ {
-		yOffset := float32(50 + i*300)
-		vector.DrawFilledRect(screen, 50, yOffset, 400, 250, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("User Machine %d", i+1), 60, int(yOffset)+10, color.White)
-		for j, u := range g.UserMachines[i] {
-			var c color.Color = color.White
-			if g.animationStep > stepIdle && g.currentUserIndex == j {
-				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
-			}
-			g.drawScaledText(screen, fmt.Sprintf("UserID: %d, Name: %s", u.UserID, u.Name), 60, int(yOffset)+60+j*30, c)
-		}
-	}
-
-	// Order Machines
-	for i := 0; i < 2; i++ { // i is the order machine index
-		yOffset := float32(50 + i*300)
-		vector.DrawFilledRect(screen, 750, yOffset, 550, 250, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Order Machine %d", i+1), 760, int(yOffset)+10, color.White)
-		for j, o := range g.OrderMachines[i] { // j is the row index
-			var c color.Color = color.White
-
-			// Check for highlighting for both users (0 and 1)
-			isScanning := false
-			isFound := false
-
-			for userIndex := 0; userIndex < 2; userIndex++ {
-				// Check if this row is being scanned by this user
-				if g.animationStep == stepScanningOrderTable && !g.matchFound[userIndex] && g.orderScanMachineIndex[userIndex] == i && g.orderScanIndex[userIndex] == j {
-					isScanning = true
-				}
-				// Check if this row is the found match for this user
-				if g.matchFound[userIndex] && g.currentOrderMachineIndex[userIndex] == i && g.currentOrderIndex[userIndex] == j {
-					isFound = true
-				}
-			}
-
-			if isFound {
-				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff} // Yellow
-			} else if isScanning {
-				c = color.RGBA{B: 0xff, A: 0xff} // Blue
-			}
-
-			g.drawScaledText(screen, fmt.Sprintf("OrderID: %d, UserID: %d, Item: %s", o.OrderID, o.UserID, o.Item), 760, int(yOffset)+60+j*30, c)
-		}
+func (g *Game) joinedLabel(j sim.JoinedData) string {
+	schema := g.engine.Schema
+	switch {
+	case g.engine.JoinType == sim.SemiJoin || g.engine.JoinType == sim.AntiJoin:
+		// Only the User columns are returned.
+		return g.userLabel(j.User)
+	case j.Null:
+		return fmt.Sprintf("%s, %s: NULL, %s: NULL", g.userLabel(j.User), schema.OrderID, schema.Item)
 	}
+	return fmt.Sprintf("%s, %s: %d, %s: %s", g.userLabel(j.User), schema.OrderID, j.Order.OrderID, schema.Item, j.Order.Item)
 }
 
-func (g *Game) drawTablesJOIN3(screen *ebiten.Image) {
-	// User Machines
-	for i := 0; i < 2; i++ {
-		yOffset := float32(50 + i*300)
-		vector.DrawFilledRect(screen, 50, yOffset, 400, 250, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("User Machine %d", i+1), 60, int(yOffset)+10, color.White)
-		for j, u := range g.UserMachines[i] {
-			var c color.Color = color.White
-			if g.animationStep >= stepUserToIndexRequest && g.currentUserIndex == j {
-				c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
-			}
-			g.drawScaledText(screen, fmt.Sprintf("UserID: %d, Name: %s", u.UserID, u.Name), 60, int(yOffset)+60+j*30, c)
-		}
-	}
-
-	// Index Machines
-	for i := 0; i < 2; i++ {
-		yOffset := float32(50 + i*300)
-		vector.DrawFilledRect(screen, 550, yOffset, 400, 250, color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Index Machine %d", i+1), 560, int(yOffset)+10, color.White)
-		for j, entry := range g.IndexMachines[i] {
-			var c color.Color = color.White
-			if g.animationStep >= stepUserToIndexResponse {
-				if (g.currentIndexMachineIndex[0] == i && g.currentIndexIndex[0] == j) || (g.currentIndexMachineIndex[1] == i && g.currentIndexIndex[1] == j) {
-					c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
-				}
-			}
-			g.drawScaledText(screen, fmt.Sprintf("UserID: %d, OrderID: %d", entry.UserID, entry.OrderID), 560, int(yOffset)+60+j*30, c)
-		}
-	}
-
-	// Order Machines
-	for i := 0; i < 2; i++ {
-		yOffset := float32(50 + i*300)
-		vector.DrawFilledRect(screen, 1050, yOffset, 500, 250, color.RGBA{R: 0x30, G: 0x30, B: 0x30, A: 0xff}, false)
-		g.drawScaledText(screen, fmt.Sprintf("Order Machine %d", i+1), 1060, int(yOffset)+10, color.White)
-		for j, o := range g.OrderMachines[i] {
-			var c color.Color = color.White
-			if g.animationStep == stepIndexToOrderResponse {
-				if (g.currentOrderMachineIndex[0] == i && g.currentOrderIndex[0] == j) || (g.currentOrderMachineIndex[1] == i && g.currentOrderIndex[1] == j) {
-					c = color.RGBA{R: 0xff, G: 0xff, A: 0xff}
-				}
-			}
-			g.drawScaledText(screen, fmt.Sprintf("OrderID: %d, UserID: %d, Item: %s", o.OrderID, o.UserID, o.Item), 1060, int(yOffset)+60+j*30, c)
-		}
-	}
+// groupByQuery returns the query the GROUP BY scenarios run.
+func (g *Game) groupByQuery(columns []sim.Column, agg sim.Aggregate) string {
+	schema := g.engine.Schema
+	keys := columnNames(schema, columns)
+	return fmt.Sprintf("SELECT %s, %s FROM %s GROUP BY %s", keys, agg.Expr(schema), schema.OrderTable, keys)
 }
 
-func (g *Game) drawJoinedTable(screen *ebiten.Image, yPos float32) {
-	if !g.showJoined {
-		return
-	}
-	vector.DrawFilledRect(screen, 50, yPos, 1500, 300, color.RGBA{R: 0x30, G: 0x30, B: 0x60, A: 0xff}, false)
-	g.drawScaledText(screen, "JOIN Result", 60, int(yPos)+10, color.White)
-	for i, j := range g.Joined {
-		row := i / 2
-		col := i % 2
-		x := 60 + col*770
-		y := int(yPos) + 60 + row*30
-		g.drawScaledText(screen, fmt.Sprintf("UserID: %d, Name: %s, OrderID: %d, Item: %s", j.User.UserID, j.User.Name, j.Order.OrderID, j.Order.Item), x, y, color.White)
+// columnNames lists the names of columns in schema.
+func columnNames(schema sim.Schema, columns []sim.Column) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name(schema)
 	}
+	return strings.Join(names, ", ")
 }
 
-// --- Helpers ---
-
-func (g *Game) setPacketStartPosition() {
-	switch g.AnimationType {
-	case "JOIN2":
-		g.setPacketStartPositionJOIN2()
-	case "JOIN3":
-		g.setPacketStartPositionJOIN3()
-	case "GROUPBY1":
-		// No packets to set at start
-	default: // JOIN1
-		userY := 110 + float32(g.currentUserIndex*30) + 12
-		g.packetX[0] = 450
-		g.packetY[0] = userY
-		g.packetStartX[0], g.packetStartY[0] = g.packetX[0], g.packetY[0]
+// groupedHeader and groupedRow show an order with its GROUP BY columns
+// first, followed by OrderID and Price.
+func (g *Game) groupedHeader(columns []sim.Column) string {
+	schema := g.engine.Schema
+	names := []string{columnNames(schema, columns)}
+	if !slices.Contains(columns, sim.ColumnOrderID) {
+		names = append(names, schema.OrderID)
 	}
+	return strings.Join(append(names, schema.Price), ",")
 }
 
-func (g *Game) setPacketStartPositionJOIN2() {
-	for i := 0; i < 2; i++ {
-		userTableYOffset := float32(50)
-		if i == 1 {
-			userTableYOffset = 350
-		}
-		userY := userTableYOffset + 60 + float32(g.currentUserIndex*30) + 12
-		g.packetX[i] = 450
-		g.packetY[i] = userY
-		g.packetStartX[i], g.packetStartY[i] = g.packetX[i], g.packetY[i]
+func groupedRow(columns []sim.Column, o sim.Order) string {
+	var values []string
+	for _, c := range columns {
+		values = append(values, c.Value(o))
 	}
+	if !slices.Contains(columns, sim.ColumnOrderID) {
+		values = append(values, strconv.Itoa(o.OrderID))
+	}
+	return strings.Join(append(values, strconv.Itoa(o.Price)), ",")
 }
 
-func (g *Game) setPacketStartPositionJOIN3() {
-	for i := 0; i < 2; i++ {
-		userY := float32(50 + 60 + g.currentUserIndex*30 + 12)
-		if i == 1 {
-			userY += 300
-		}
-		g.packetStartX[i] = 450
-		g.packetStartY[i] = userY
-		g.packetX[i], g.packetY[i] = g.packetStartX[i], g.packetY[i]
+// keyLabel shows the key of a group, in parentheses if it has several
+// columns.
+func keyLabel(res sim.AggregationResult) string {
+	if len(res.Key) == 1 {
+		return res.Key[0]
 	}
+	return "(" + strings.Join(res.Key, ", ") + ")"
 }
 
-func (g *Game) setupPacket(i int) {
-	deltaX := g.packetTargetX[i] - g.packetStartX[i]
-	deltaY := g.packetTargetY[i] - g.packetStartY[i]
-	distance := float32(math.Sqrt(float64(deltaX*deltaX + deltaY*deltaY)))
-	if distance > 0 {
-		g.packetSpeedX[i] = g.packetSpeed * deltaX / distance
-		g.packetSpeedY[i] = g.packetSpeed * deltaY / distance
+// resultLabel shows the final value of a group under agg.
+func resultLabel(agg sim.Aggregate, res sim.AggregationResult) string {
+	if agg == sim.AggAvg {
+		return fmt.Sprintf("%s: %.1f", keyLabel(res), agg.Value(res.Partial))
 	}
+	return fmt.Sprintf("%s: %d", keyLabel(res), int(agg.Value(res.Partial)))
 }
 
-func (g *Game) movePacket(i int) bool {
-	// Check if the packet has moved past the target
-	dx_total := g.packetTargetX[i] - g.packetStartX[i]
-	dy_total := g.packetTargetY[i] - g.packetStartY[i]
-	dx_moved := g.packetX[i] - g.packetStartX[i]
-	dy_moved := g.packetY[i] - g.packetStartY[i]
-	if dx_total*dx_total+dy_total*dy_total <= dx_moved*dx_moved+dy_moved*dy_moved {
-		return true
+// partialLabel shows the state of a group that a tier ships to the next.
+func partialLabel(agg sim.Aggregate, res sim.AggregationResult) string {
+	switch agg {
+	case sim.AggAvg:
+		return fmt.Sprintf("%s: sum %d, count %d", keyLabel(res), res.Sum, res.Count)
+	case sim.AggCountDistinct:
+		ids := make([]string, len(res.Distinct))
+		for i, id := range res.Distinct {
+			ids[i] = strconv.Itoa(id)
+		}
+		return fmt.Sprintf("%s: {%s}", keyLabel(res), strings.Join(ids, ","))
 	}
-
-	g.packetX[i] += g.packetSpeedX[i]
-	g.packetY[i] += g.packetSpeedY[i]
-	return false
+	return resultLabel(agg, res)
 }
 
-func (g *Game) drawScaledText(screen *ebiten.Image, str string, x, y int, clr color.Color) {
-	bounds := text.BoundString(basicfont.Face7x13, str)
-	w, h := bounds.Dx(), bounds.Dy()
-	if w == 0 || h == 0 {
-		return
-	}
-	offscreen := ebiten.NewImage(w, h)
-	text.Draw(offscreen, str, basicfont.Face7x13, -bounds.Min.X, -bounds.Min.Y, clr)
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Scale(textScale, textScale)
-	op.GeoM.Translate(float64(x), float64(y))
-	screen.DrawImage(offscreen, op)
+// packetPosition returns the screen position of a packet on its way between two endpoints.
+func (g *Game) packetPosition(p sim.Packet) (float32, float32) {
+	fromX, fromY := g.scenario.Anchor(g.layout, p.From, true)
+	toX, toY := g.scenario.Anchor(g.layout, p.To, false)
+	t := p.Progress()
+	return fromX + (toX-fromX)*t, fromY + (toY-fromY)*t
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/sinmetal/spanneranime/layout"
+	"github.com/sinmetal/spanneranime/sim"
+)
+
+// Scenario is a sim.Scenario that also knows how to draw itself.
+// Each scenario lives in its own file and registers itself from init.
+type Scenario interface {
+	sim.Scenario
+
+	// Draw renders the scenario for the current state of the engine.
+	Draw(g *Game, screen *ebiten.Image)
+
+	// Tables describes the tables to lay out. It is called after Setup, so
+	// the topology and row counts are known.
+	Tables() []layout.Table
+
+	// Anchor returns the screen position of an endpoint in l. Packets leave
+	// a table from one edge and arrive at the facing edge of the next one,
+	// so outgoing tells which of the two is asked for.
+	Anchor(l *layout.Layout, ep sim.Endpoint, outgoing bool) (float32, float32)
+}
+
+var scenarios = map[string]func() Scenario{}
+
+// register adds a scenario to the registry under its Name.
+func register(newScenario func() Scenario) {
+	name := newScenario().Name()
+	if _, ok := scenarios[name]; ok {
+		panic(fmt.Sprintf("scenario %s registered twice", name))
+	}
+	scenarios[name] = newScenario
+}
+
+// lookupScenario returns a new instance of the named scenario.
+func lookupScenario(name string) (Scenario, error) {
+	newScenario, ok := scenarios[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scenario %q", name)
+	}
+	return newScenario(), nil
+}
+
+// scenarioNames returns the registered scenario names in sorted order.
+func scenarioNames() []string {
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,152 @@
+// Package layout places tables, splits and rows on the screen.
+//
+// A scenario describes its tables once, as areas of a DesignWidth x
+// DesignHeight canvas and the number of rows of every split. New scales the
+// areas to the real screen size and divides them into split and row
+// rectangles, so drawing code and packet anchors ask the Layout where things
+// are instead of repeating the arithmetic.
+package layout
+
+// The canvas Table areas are given on.
+const (
+	DesignWidth  = 1600
+	DesignHeight = 1000
+)
+
+// Rect is a rectangle in screen pixels.
+type Rect struct {
+	X, Y, W, H float32
+}
+
+// Side is an edge of a Rect.
+type Side int
+
+const (
+	Left Side = iota
+	Right
+	Top
+	Bottom
+)
+
+// Anchor returns the middle of the given edge, where packets leave and
+// arrive.
+func (r Rect) Anchor(side Side) (float32, float32) {
+	switch side {
+	case Left:
+		return r.X, r.Y + r.H/2
+	case Right:
+		return r.X + r.W, r.Y + r.H/2
+	case Top:
+		return r.X + r.W/2, r.Y
+	default:
+		return r.X + r.W/2, r.Y + r.H
+	}
+}
+
+// Table describes how the splits of one table share an area.
+type Table struct {
+	Name string
+
+	// Area is the rectangle of the whole table on the design canvas.
+	Area Rect
+
+	// Horizontal places the splits side by side instead of stacking them.
+	Horizontal bool
+
+	// Rows is the number of rows of every split. A table without Rows has
+	// one split whose rows are RowHeight apart.
+	Rows []int
+
+	// Gap is the space between two splits, Header the space above the first
+	// row of a split and RowHeight the distance between rows. Rows are
+	// squeezed when they do not fit their split.
+	Gap       float32
+	Header    float32
+	RowHeight float32
+
+	// MaxWidth, if set, caps the width of a split, which is then centred in
+	// its share of the area.
+	MaxWidth float32
+}
+
+type table struct {
+	splits []Rect
+	pitch  []float32
+	header float32
+}
+
+// Layout is the position of every table on a screen of a given size.
+type Layout struct {
+	Width, Height float32
+
+	sx, sy float32
+	tables map[string]*table
+}
+
+// New lays out tables on a width x height screen.
+func New(width, height float32, tables ...Table) *Layout {
+	sx, sy := width/DesignWidth, height/DesignHeight
+	l := &Layout{Width: width, Height: height, sx: sx, sy: sy, tables: map[string]*table{}}
+	for _, t := range tables {
+		area := Rect{X: t.Area.X * sx, Y: t.Area.Y * sy, W: t.Area.W * sx, H: t.Area.H * sy}
+		n := max(len(t.Rows), 1)
+		lt := &table{header: t.Header * sy}
+		for i := 0; i < n; i++ {
+			r := area
+			if t.Horizontal {
+				gap := t.Gap * sx
+				slot := (area.W + gap) / float32(n)
+				r.X, r.W = area.X+float32(i)*slot, slot-gap
+				if limit := t.MaxWidth * sx; limit > 0 && r.W > limit {
+					r.X, r.W = r.X+(r.W-limit)/2, limit
+				}
+			} else {
+				gap := t.Gap * sy
+				slot := (area.H + gap) / float32(n)
+				r.Y, r.H = area.Y+float32(i)*slot, slot-gap
+				if limit := t.MaxWidth * sx; limit > 0 && r.W > limit {
+					r.W = limit
+				}
+			}
+			pitch := t.RowHeight * sy
+			if i < len(t.Rows) && t.Rows[i] > 0 && lt.header+float32(t.Rows[i])*pitch > r.H {
+				pitch = (r.H - lt.header) / float32(t.Rows[i])
+			}
+			lt.splits = append(lt.splits, r)
+			lt.pitch = append(lt.pitch, pitch)
+		}
+		l.tables[t.Name] = lt
+	}
+	return l
+}
+
+// Split returns the rectangle of split s of the named table.
+func (l *Layout) Split(name string, s int) Rect {
+	t, ok := l.tables[name]
+	if !ok || s < 0 || s >= len(t.splits) {
+		return Rect{}
+	}
+	return t.splits[s]
+}
+
+// Row returns the rectangle of row k in split s of the named table.
+func (l *Layout) Row(name string, s, k int) Rect {
+	t, ok := l.tables[name]
+	if !ok || s < 0 || s >= len(t.splits) {
+		return Rect{}
+	}
+	split := t.splits[s]
+	pitch := t.pitch[s]
+	return Rect{X: split.X, Y: split.Y + t.header + float32(k)*pitch, W: split.W, H: pitch}
+}
+
+// Point converts a point on the design canvas to screen pixels.
+func (l *Layout) Point(x, y float32) (float32, float32) {
+	return x * l.sx, y * l.sy
+}
+
+// Scale is the factor for sizes that must keep their shape, such as text and
+// circles: the smaller of the horizontal and vertical scale.
+func (l *Layout) Scale() float32 {
+	return min(l.sx, l.sy)
+}
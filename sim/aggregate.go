@@ -0,0 +1,189 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aggregate selects the aggregate function of the GROUP BY scenarios.
+type Aggregate int
+
+const (
+	// AggSum is SUM(Price).
+	AggSum Aggregate = iota
+	// AggCount is COUNT(*).
+	AggCount
+	// AggAvg is AVG(Price). Tiers ship a sum and a count per group, since
+	// averages of averages are wrong.
+	AggAvg
+	// AggMin is MIN(Price).
+	AggMin
+	// AggMax is MAX(Price).
+	AggMax
+	// AggCountDistinct is COUNT(DISTINCT UserID). Tiers ship the set of
+	// UserIDs per group, since counts of distinct values cannot be added.
+	AggCountDistinct
+)
+
+var aggregateNames = []string{"SUM", "COUNT", "AVG", "MIN", "MAX", "COUNT DISTINCT"}
+
+// AggregateScenario is a Scenario whose aggregate function can be chosen.
+type AggregateScenario interface {
+	Scenario
+
+	// SetAggregate makes the scenario compute a.
+	SetAggregate(a Aggregate) error
+}
+
+// aggregation holds the aggregate function of a scenario.
+type aggregation struct {
+	aggregate Aggregate
+}
+
+func (g *aggregation) SetAggregate(a Aggregate) error {
+	if a < 0 || int(a) >= len(aggregateNames) {
+		return fmt.Errorf("unknown aggregate %v", a)
+	}
+	g.aggregate = a
+	return nil
+}
+
+// Aggregate returns the aggregate function, SUM unless set.
+func (g *aggregation) Aggregate() Aggregate {
+	return g.aggregate
+}
+
+func (a Aggregate) String() string {
+	if a < 0 || int(a) >= len(aggregateNames) {
+		return fmt.Sprintf("Aggregate(%d)", int(a))
+	}
+	return aggregateNames[a]
+}
+
+// ParseAggregate parses "sum", "count", "avg", "min", "max" or
+// "count-distinct".
+func ParseAggregate(s string) (Aggregate, error) {
+	switch strings.ToLower(s) {
+	case "sum":
+		return AggSum, nil
+	case "count":
+		return AggCount, nil
+	case "avg":
+		return AggAvg, nil
+	case "min":
+		return AggMin, nil
+	case "max":
+		return AggMax, nil
+	case "count-distinct", "distinct":
+		return AggCountDistinct, nil
+	}
+	return AggSum, fmt.Errorf("unknown aggregate %q, want sum, count, avg, min, max or count-distinct", s)
+}
+
+// Expr returns the aggregate as it is written in SQL, with the column names
+// of schema.
+func (a Aggregate) Expr(schema Schema) string {
+	switch a {
+	case AggCount:
+		return "COUNT(*)"
+	case AggCountDistinct:
+		return fmt.Sprintf("COUNT(DISTINCT %s)", schema.OrderUserID)
+	}
+	return fmt.Sprintf("%s(%s)", a, schema.Price)
+}
+
+// Value returns the final value of a group. Only AVG has a fraction.
+func (a Aggregate) Value(p Partial) float64 {
+	switch a {
+	case AggCount:
+		return float64(p.Count)
+	case AggAvg:
+		if p.Count == 0 {
+			return 0
+		}
+		return float64(p.Sum) / float64(p.Count)
+	case AggMin:
+		return float64(p.Min)
+	case AggMax:
+		return float64(p.Max)
+	case AggCountDistinct:
+		return float64(len(p.Distinct))
+	}
+	return float64(p.Sum)
+}
+
+// StateSize returns the number of values a tier ships for a group.
+func (a Aggregate) StateSize(p Partial) int {
+	switch a {
+	case AggAvg:
+		return 2
+	case AggCountDistinct:
+		return len(p.Distinct)
+	}
+	return 1
+}
+
+// Partial is the state of one group that a tier ships to the next. Every
+// aggregate is kept so that any of them can be shown.
+type Partial struct {
+	Sum   int
+	Count int
+	Min   int
+	Max   int
+
+	// Distinct is the sorted set of UserIDs of the group.
+	Distinct []int
+}
+
+// add aggregates the row o into p.
+func (p *Partial) add(o Order) {
+	p.merge(Partial{Sum: o.Price, Count: 1, Min: o.Price, Max: o.Price, Distinct: []int{o.UserID}})
+}
+
+// merge combines the partial state q of another tier into p.
+func (p *Partial) merge(q Partial) {
+	if q.Count == 0 {
+		return
+	}
+	if p.Count == 0 || q.Min < p.Min {
+		p.Min = q.Min
+	}
+	if p.Count == 0 || q.Max > p.Max {
+		p.Max = q.Max
+	}
+	p.Sum += q.Sum
+	p.Count += q.Count
+	for _, id := range q.Distinct {
+		i := sort.SearchInts(p.Distinct, id)
+		if i < len(p.Distinct) && p.Distinct[i] == id {
+			continue
+		}
+		p.Distinct = append(p.Distinct, 0)
+		copy(p.Distinct[i+1:], p.Distinct[i:])
+		p.Distinct[i] = id
+	}
+}
+
+// groups collects the partial state of every key.
+type groups map[string]*AggregationResult
+
+// get returns the state of key, creating it if needed.
+func (g groups) get(key []string) *Partial {
+	res, ok := g[keyString(key)]
+	if !ok {
+		res = &AggregationResult{Key: key}
+		g[keyString(key)] = res
+	}
+	return &res.Partial
+}
+
+// results converts the groups into results ordered by key.
+func (g groups) results() []AggregationResult {
+	var agg []AggregationResult
+	for _, res := range g {
+		agg = append(agg, *res)
+	}
+	sort.Slice(agg, func(i, j int) bool { return keyLess(agg[i].Key, agg[j].Key) })
+	return agg
+}
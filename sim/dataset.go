@@ -0,0 +1,100 @@
+package sim
+
+import "fmt"
+
+// Schema names the tables and columns that User and Order rows are shown as.
+type Schema struct {
+	UserTable   string
+	UserID      string
+	UserName    string
+	OrderTable  string
+	OrderID     string
+	OrderUserID string
+	Item        string
+	Price       string
+}
+
+// DefaultSchema is the schema of the generated datasets.
+var DefaultSchema = Schema{
+	UserTable:   "User",
+	UserID:      "UserID",
+	UserName:    "Name",
+	OrderTable:  "Order",
+	OrderID:     "OrderID",
+	OrderUserID: "UserID",
+	Item:        "Item",
+	Price:       "Price",
+}
+
+// Dataset is table data loaded from a scenario file.
+type Dataset struct {
+	// Scenario is the scenario the file asks for, if any.
+	Scenario string
+	Schema   Schema
+
+	// Users and Orders hold the rows of every split in key order.
+	// Users is empty when the file defines a single table.
+	Users  [][]User
+	Orders [][]Order
+}
+
+// DatasetScenario is a Scenario that can run on a loaded Dataset instead of
+// generated data.
+type DatasetScenario interface {
+	Scenario
+
+	// UseDataset checks that d fits the scenario and makes Setup use it.
+	UseDataset(d *Dataset) error
+}
+
+// needUsers checks that d has a User table with the same number of users in
+// every split, so that the splits can walk their users in lockstep.
+func (d *Dataset) needUsers(scenario string) error {
+	if len(d.Users) == 0 {
+		return fmt.Errorf("%s needs two tables, %s has no parent table", scenario, d.Schema.OrderTable)
+	}
+	if len(d.Users[0]) == 0 {
+		return fmt.Errorf("%s needs rows in every split of %s, split 1 is empty", scenario, d.Schema.UserTable)
+	}
+	for i, split := range d.Users {
+		if len(split) != len(d.Users[0]) {
+			return fmt.Errorf("%s needs the same number of rows in every split of %s, split %d has %d and split 1 has %d",
+				scenario, d.Schema.UserTable, i+1, len(split), len(d.Users[0]))
+		}
+	}
+	return nil
+}
+
+// needParents checks that every order of d has a user, so that it can be
+// stored in the split of its user.
+func (d *Dataset) needParents(scenario string) error {
+	users := map[int]bool{}
+	for _, u := range d.allUsers() {
+		users[u.UserID] = true
+	}
+	for _, o := range d.allOrders() {
+		if !users[o.UserID] {
+			return fmt.Errorf("%s needs a parent %s for every %s row, %s %d has none",
+				scenario, d.Schema.UserTable, d.Schema.OrderTable, d.Schema.OrderID, o.OrderID)
+		}
+	}
+	return nil
+}
+
+// allUsers returns the users of every split in key order.
+func (d *Dataset) allUsers() []User {
+	var users []User
+	for _, split := range d.Users {
+		users = append(users, split...)
+	}
+	return users
+}
+
+// allOrders returns the orders of every split in key order.
+func (d *Dataset) allOrders() []Order {
+	var orders []Order
+	for _, split := range d.Orders {
+		orders = append(orders, split...)
+	}
+	return orders
+}
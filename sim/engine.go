@@ -0,0 +1,262 @@
+package sim
+
+import (
+	"image/color"
+	"math/rand"
+)
+
+// TicksPerSecond matches the default update rate of ebiten.
+const TicksPerSecond = 60
+
+// scanInterval is the number of ticks between two row scans.
+const scanInterval = TicksPerSecond / 5
+
+type Step int
+
+// Steps shared by several scenarios. Every other step is declared by its
+// scenario, in its own file, counting up from FirstScenarioStep.
+const (
+	StepIdle Step = iota
+	StepRequesting
+	StepResponding
+	StepJoining
+	StepFinished
+	StepNextUser
+	StepRestart
+
+	// StepPaused holds after a JOIN result before moving to the next user.
+	StepPaused
+	// StepPauseBeforeRestart holds the result of a run before starting over.
+	StepPauseBeforeRestart
+
+	// FirstScenarioStep is the first step a scenario declares itself. Steps
+	// of different scenarios may share a value.
+	FirstScenarioStep
+)
+
+// restartPause is the number of ticks the result of a run is shown.
+const restartPause = 3 * TicksPerSecond
+
+// hold is a step that lasts a fixed number of ticks before moving on.
+type hold struct {
+	ticks int
+	next  Step
+}
+
+// Packet is a message travelling between two endpoints.
+// It takes Duration ticks to arrive.
+type Packet struct {
+	Active   bool
+	From     Endpoint
+	To       Endpoint
+	Elapsed  int
+	Duration int
+
+	// Payload describes what the packet carries and is drawn next to it.
+	Payload string
+
+	// Color of the packet, or nil for the default.
+	Color color.Color
+
+	// OnArrive, if set, runs once when the packet arrives.
+	OnArrive func()
+}
+
+// Arrived reports whether the packet has reached its destination.
+func (p Packet) Arrived() bool {
+	return p.Elapsed >= p.Duration
+}
+
+// Progress returns how far the packet has travelled, from 0 to 1.
+func (p Packet) Progress() float32 {
+	if p.Duration <= 0 || p.Arrived() {
+		return 1
+	}
+	return float32(p.Elapsed) / float32(p.Duration)
+}
+
+type Engine struct {
+	// Scenario drives the steps of the animation.
+	Scenario Scenario
+
+	// Schema names the tables and columns of the dataset.
+	Schema Schema
+
+	Step       Step
+	AutoStart  bool
+	ShowJoined bool
+	Joined     []JoinedData
+
+	// JoinType selects the rows the JOIN scenarios return.
+	JoinType JoinType
+
+	// Packets in flight. Scenarios that move in lockstep use one packet per
+	// split through place and send, which grow it as needed; fan-out
+	// scenarios spawn packets, which reuse the inactive ones.
+	Packets []Packet
+
+	// PacketTicks is the number of ticks a packet takes to arrive.
+	PacketTicks int
+
+	// RPCs sent since the animation last started, in order.
+	RPCs []RPC
+
+	// Scheduler paces the animation; pause and speed changes go through it.
+	Scheduler *Scheduler
+
+	tick      int
+	scanTicks int
+
+	// holding is the hold of the current step, if it has one.
+	holding hold
+}
+
+// NewEngine sets up an idle engine for s. All random data is drawn from rng,
+// so the same seed always produces the same dataset.
+func NewEngine(s Scenario, rng *rand.Rand) *Engine {
+	e := &Engine{
+		Scenario:  s,
+		Schema:    DefaultSchema,
+		Step:      StepIdle,
+		Scheduler: NewScheduler(),
+	}
+	s.Setup(e, rng)
+	return e
+}
+
+// Start begins the animation from the first step.
+func (e *Engine) Start() {
+	e.Joined = []JoinedData{}
+	e.RPCs = nil
+	e.scanTicks = 0
+	e.Scheduler.held = 0
+	e.holding = hold{}
+	e.Step = e.Scenario.Reset(e)
+}
+
+// Tick advances the animation by one frame. Depending on the scheduler's
+// speed this runs zero or more simulation ticks.
+func (e *Engine) Tick() {
+	for n := e.Scheduler.Frame(); n > 0; n-- {
+		e.step()
+	}
+}
+
+// step runs a single simulation tick.
+func (e *Engine) step() {
+	e.tick++
+	if e.Step == StepIdle && e.AutoStart {
+		e.Start()
+	}
+	if e.holding.ticks > 0 {
+		if !e.Scheduler.Hold(e.holding.ticks) {
+			return
+		}
+		e.Step = e.holding.next
+		e.holding = hold{}
+	}
+	if e.Step == StepRestart {
+		e.Start()
+	}
+	e.Scenario.Update(e)
+}
+
+// hold moves to step, which lasts the given number of ticks before moving on
+// to next.
+func (e *Engine) hold(step Step, ticks int, next Step) {
+	e.Step = step
+	e.holding = hold{ticks: ticks, next: next}
+}
+
+// pauseBeforeRestart shows the result of a run for a few seconds before
+// starting over.
+func (e *Engine) pauseBeforeRestart() {
+	e.hold(StepPauseBeforeRestart, restartPause, StepRestart)
+}
+
+// scanDue reports whether a row scan is due on this tick.
+func (e *Engine) scanDue() bool {
+	e.scanTicks++
+	if e.scanTicks < scanInterval {
+		return false
+	}
+	e.scanTicks = 0
+	return true
+}
+
+// packet returns packet i, growing Packets if needed.
+func (e *Engine) packet(i int) *Packet {
+	for len(e.Packets) <= i {
+		e.Packets = append(e.Packets, Packet{})
+	}
+	return &e.Packets[i]
+}
+
+// send starts packet i towards to and records the RPC.
+func (e *Engine) send(i int, to Endpoint) {
+	p := e.packet(i)
+	p.Active = true
+	p.To = to
+	p.Elapsed = 0
+	p.Duration = e.PacketTicks
+	e.RPCs = append(e.RPCs, RPC{Tick: e.tick, From: p.From, To: to})
+}
+
+// spawn sends a new packet carrying payload from from to to, reusing an
+// inactive packet if there is one, and records the RPC. onArrive, if not nil,
+// runs once the packet has arrived.
+func (e *Engine) spawn(from, to Endpoint, payload string, onArrive func()) *Packet {
+	i := 0
+	for i < len(e.Packets) && e.Packets[i].Active {
+		i++
+	}
+	e.place(i, from)
+	e.send(i, to)
+	p := &e.Packets[i]
+	p.Payload = payload
+	p.OnArrive = onArrive
+	return p
+}
+
+// place puts packet i on from, ready to be sent, with nothing to carry.
+func (e *Engine) place(i int, from Endpoint) {
+	p := e.packet(i)
+	p.From = from
+	p.Elapsed = 0
+	p.Payload = ""
+	p.Color = nil
+	p.OnArrive = nil
+}
+
+// movePacket advances packet i and reports whether it has arrived. OnArrive
+// runs on the tick the packet arrives.
+func (e *Engine) movePacket(i int) bool {
+	p := &e.Packets[i]
+	if p.Arrived() {
+		return true
+	}
+	p.Elapsed++
+	if p.Arrived() && p.OnArrive != nil {
+		onArrive := p.OnArrive
+		p.OnArrive = nil
+		onArrive()
+	}
+	return false
+}
+
+// moveActive advances every active packet and reports whether all of them
+// have arrived, deactivating them once they have.
+func (e *Engine) moveActive() bool {
+	done := true
+	for i := range e.Packets {
+		if e.Packets[i].Active && !e.movePacket(i) {
+			done = false
+		}
+	}
+	if done {
+		for i := range e.Packets {
+			e.Packets[i].Active = false
+		}
+	}
+	return done
+}
@@ -0,0 +1,178 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// finished reports whether the first run of a JOIN scenario is over.
+func finished(e *Engine) bool {
+	return e.Step == StepPauseBeforeRestart
+}
+
+// run starts s with the given seed and speed and returns the engine once the
+// first run is over, along with the number of frames that took.
+func run(t *testing.T, s Scenario, seed int64, speed float64) (*Engine, int) {
+	t.Helper()
+	e := NewEngine(s, rand.New(rand.NewSource(seed)))
+	e.Scheduler.SetSpeed(speed)
+	e.Start()
+	for frames := 1; frames <= 100000; frames++ {
+		e.Tick()
+		if finished(e) {
+			return e, frames
+		}
+	}
+	t.Fatalf("%s did not finish", s.Name())
+	return nil, 0
+}
+
+// rpcDataset is small enough to list every RPC of a run: Bob has two orders on
+// different splits, Cy one and Ann and Di none.
+var rpcDataset = &Dataset{
+	Schema: DefaultSchema,
+	Users: [][]User{
+		{{UserID: 1, Name: "Ann"}, {UserID: 2, Name: "Bob"}},
+		{{UserID: 3, Name: "Cy"}, {UserID: 4, Name: "Di"}},
+	},
+	Orders: [][]Order{
+		{{OrderID: 101, UserID: 2, Item: "Apple", Price: 100}, {OrderID: 102, UserID: 3, Item: "Pear", Price: 200}},
+		{{OrderID: 103, UserID: 2, Item: "Plum", Price: 300}},
+	},
+}
+
+func TestJoinRPCs(t *testing.T) {
+	users := func(split, row int) Endpoint { return Endpoint{Table: TableUsers, Split: split, Row: row} }
+	orders := func(split, row int) Endpoint { return Endpoint{Table: TableOrders, Split: split, Row: row} }
+	index := func(split, row int) Endpoint { return Endpoint{Table: TableIndex, Split: split, Row: row} }
+	tests := []struct {
+		s    DatasetScenario
+		want [][2]Endpoint
+	}{
+		// JOIN1 sees a single Order table and scans it once per user.
+		{&JOIN1{}, [][2]Endpoint{
+			{users(0, 0), orders(0, 0)},
+			{users(0, 1), orders(0, 0)},
+			{users(0, 2), orders(0, 0)},
+			{users(0, 3), orders(0, 0)},
+		}},
+		// JOIN2 walks the User splits in lockstep, every user visiting every
+		// Order split in turn.
+		{&JOIN2{}, [][2]Endpoint{
+			{users(0, 0), orders(0, 0)},
+			{users(1, 0), orders(0, 0)},
+			{users(0, 0), orders(1, 0)},
+			{users(1, 0), orders(1, 0)},
+			{users(0, 1), orders(0, 0)},
+			{users(1, 1), orders(0, 0)},
+			{users(0, 1), orders(1, 0)},
+			{users(1, 1), orders(1, 0)},
+		}},
+		// JOIN3 seeks the index for every user and fetches one Order row per
+		// entry found. Ann and Di find no entry and fetch nothing.
+		{&JOIN3{}, [][2]Endpoint{
+			{users(0, 0), index(0, 0)},
+			{users(1, 0), index(1, 1)},
+			{index(1, 1), orders(0, 1)},
+			{users(0, 1), index(0, 0)},
+			{users(1, 1), index(1, 1)},
+			{index(0, 0), orders(0, 0)},
+			{index(1, 0), orders(1, 0)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s.Name(), func(t *testing.T) {
+			if err := tt.s.UseDataset(rpcDataset); err != nil {
+				t.Fatal(err)
+			}
+			e, _ := run(t, tt.s, 1, 1)
+			var got [][2]Endpoint
+			for _, rpc := range e.RPCs {
+				got = append(got, [2]Endpoint{rpc.From, rpc.To})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RPCs:\n got %v\nwant %v", got, tt.want)
+			}
+			if len(e.Joined) != 3 {
+				t.Errorf("joined %d rows, want 3", len(e.Joined))
+			}
+		})
+	}
+}
+
+func TestEngineScheduler(t *testing.T) {
+	_, ticks := run(t, &JOIN1{}, 1, 1)
+	for _, speed := range []float64{2, 0.5, MaxSpeed, MinSpeed} {
+		want := int(math.Ceil(float64(ticks) / speed))
+		if _, frames := run(t, &JOIN1{}, 1, speed); frames != want {
+			t.Errorf("speed %v: finished after %d frames, want %d", speed, frames, want)
+		}
+	}
+}
+
+func TestEnginePause(t *testing.T) {
+	e := NewEngine(&JOIN1{}, rand.New(rand.NewSource(1)))
+	e.Start()
+	e.Tick()
+	e.Scheduler.Pause()
+	tick, step, rpcs := e.tick, e.Step, len(e.RPCs)
+	for range 1000 {
+		e.Tick()
+	}
+	if e.tick != tick || e.Step != step || len(e.RPCs) != rpcs {
+		t.Errorf("paused engine moved from tick %d to %d", tick, e.tick)
+	}
+	e.Scheduler.Resume()
+	e.Tick()
+	if e.tick != tick+1 {
+		t.Errorf("resumed engine at tick %d, want %d", e.tick, tick+1)
+	}
+}
+
+func TestEngineHold(t *testing.T) {
+	e, _ := run(t, &JOIN1{}, 1, 1)
+	ticks := 0
+	for e.Step == StepPauseBeforeRestart {
+		e.Tick()
+		ticks++
+	}
+	if ticks != restartPause {
+		t.Errorf("held for %d ticks, want %d", ticks, restartPause)
+	}
+	if len(e.RPCs) != 1 || len(e.Joined) != 0 {
+		t.Errorf("restarted with %d RPCs and %d joined rows, want 1 and 0", len(e.RPCs), len(e.Joined))
+	}
+}
+
+func TestSameSeed(t *testing.T) {
+	scenarios := []func() Scenario{
+		func() Scenario { return &JOIN1{} },
+		func() Scenario { return &JOIN2{} },
+		func() Scenario { return &JOIN3{} },
+		func() Scenario { return &JOIN4{} },
+		func() Scenario { return &JOIN5{} },
+		func() Scenario { return &JOIN6{} },
+		func() Scenario { return &JOIN7{} },
+		func() Scenario { return &JOIN8{} },
+		func() Scenario { return &JOIN9{} },
+		func() Scenario { return &JOIN10{} },
+		func() Scenario { return &GROUPBY1{} },
+		func() Scenario { return &GROUPBY2{} },
+		func() Scenario { return &GROUPBY3{} },
+		func() Scenario { return &GROUPBY4{} },
+	}
+	for _, newScenario := range scenarios {
+		a, b, c := newScenario(), newScenario(), newScenario()
+		NewEngine(a, rand.New(rand.NewSource(7)))
+		NewEngine(b, rand.New(rand.NewSource(7)))
+		NewEngine(c, rand.New(rand.NewSource(8)))
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s: seed 7 produced two different datasets", a.Name())
+		}
+		if reflect.DeepEqual(a, c) {
+			t.Errorf("%s: seeds 7 and 8 produced the same dataset", a.Name())
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ordersPerSplit is the number of orders generated for every split of the
+// scenarios over a single Order table.
+const ordersPerSplit = 10
+
+// newItemOrders returns n orders of random users, items and prices.
+func newItemOrders(rng *rand.Rand, items []string, n int) []Order {
+	orders := make([]Order, n)
+	for i := range orders {
+		orders[i] = Order{
+			OrderID: 1000 + i,
+			UserID:  rng.Intn(100),
+			Item:    items[rng.Intn(len(items))],
+			Price:   100 + rng.Intn(900),
+		}
+	}
+	return orders
+}
+
+// Column is a column of the Order table a GROUP BY can use.
+type Column int
+
+const (
+	ColumnItem Column = iota
+	ColumnUserID
+	ColumnOrderID
+)
+
+// ParseGroupBy parses a comma separated list of "item", "userid" and
+// "orderid", such as "userid,item".
+func ParseGroupBy(s string) ([]Column, error) {
+	var columns []Column
+	seen := map[Column]bool{}
+	for _, name := range strings.Split(s, ",") {
+		var c Column
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "item":
+			c = ColumnItem
+		case "userid", "user":
+			c = ColumnUserID
+		case "orderid", "order":
+			c = ColumnOrderID
+		default:
+			return nil, fmt.Errorf("unknown GROUP BY column %q, want item, userid or orderid", name)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("GROUP BY column %q appears twice", name)
+		}
+		seen[c] = true
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// Name returns the name of the column in schema.
+func (c Column) Name(schema Schema) string {
+	switch c {
+	case ColumnUserID:
+		return schema.OrderUserID
+	case ColumnOrderID:
+		return schema.OrderID
+	}
+	return schema.Item
+}
+
+// Value returns the value of the column in o.
+func (c Column) Value(o Order) string {
+	switch c {
+	case ColumnUserID:
+		return strconv.Itoa(o.UserID)
+	case ColumnOrderID:
+		return strconv.Itoa(o.OrderID)
+	}
+	return o.Item
+}
+
+// GroupByScenario is a Scenario whose GROUP BY columns can be chosen.
+type GroupByScenario interface {
+	Scenario
+
+	// SetGroupBy makes Setup and Update group by columns.
+	SetGroupBy(columns []Column) error
+}
+
+// grouping holds the GROUP BY columns of a scenario.
+type grouping struct {
+	columns []Column
+}
+
+func (g *grouping) SetGroupBy(columns []Column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("GROUP BY needs at least one column")
+	}
+	g.columns = columns
+	return nil
+}
+
+// GroupBy returns the GROUP BY columns, Item unless set.
+func (g *grouping) GroupBy() []Column {
+	if len(g.columns) == 0 {
+		return []Column{ColumnItem}
+	}
+	return g.columns
+}
+
+// key returns the values of the GROUP BY columns in o.
+func (g *grouping) key(o Order) []string {
+	columns := g.GroupBy()
+	key := make([]string, len(columns))
+	for i, c := range columns {
+		key[i] = c.Value(o)
+	}
+	return key
+}
+
+// Groups returns the number of distinct keys in splits.
+func (g *grouping) Groups(splits ...[]Order) int {
+	keys := map[string]bool{}
+	for _, split := range splits {
+		for _, o := range split {
+			keys[keyString(g.key(o))] = true
+		}
+	}
+	return len(keys)
+}
+
+// sortByKey sorts orders by their key, then OrderID.
+func (g *grouping) sortByKey(orders []Order) {
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := g.key(orders[i]), g.key(orders[j])
+		if !sameKey(a, b) {
+			return keyLess(a, b)
+		}
+		return orders[i].OrderID < orders[j].OrderID
+	})
+}
+
+// keyString joins the values of a key into a map key.
+func keyString(key []string) string {
+	return strings.Join(key, "\x00")
+}
+
+func sameKey(a, b []string) bool {
+	return keyString(a) == keyString(b)
+}
+
+// keyLess orders keys column by column, comparing INT64 values as numbers.
+func keyLess(a, b []string) bool {
+	for i := range a {
+		if a[i] == b[i] {
+			continue
+		}
+		x, errX := strconv.Atoi(a[i])
+		y, errY := strconv.Atoi(b[i])
+		if errX == nil && errY == nil {
+			return x < y
+		}
+		return a[i] < b[i]
+	}
+	return false
+}
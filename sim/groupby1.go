@@ -0,0 +1,265 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Steps of GROUPBY1, which GROUPBY4 shares. The mid-tier steps repeat for
+// every level of the aggregation tree.
+const (
+	StepGroupByBottomLayer Step = FirstScenarioStep + iota
+	StepPauseBeforeSendToMiddleLayer
+	StepSendToMiddleLayer
+	StepRespondingToMiddleLayer
+	StepPauseBeforeGroupByMiddleLayer
+	StepGroupByMiddleLayer
+	StepPauseBeforeSendToTopLayer
+	StepSendToTopLayer
+	StepRespondingToTopLayer
+	StepPauseBeforeGroupByTopLayer
+	StepGroupByTopLayer
+)
+
+// sendPause and mergePause are the number of ticks GROUPBY1 waits before
+// sending results up and before merging the results that arrived.
+const (
+	sendPause  = 2 * TicksPerSecond
+	mergePause = 1 * TicksPerSecond
+)
+
+// GROUPBY1 aggregates by the GROUP BY columns, Item unless set, on every
+// split and merges the partial results through a tree of aggregation
+// servers: every server merges the results of FanIn neighbouring servers
+// of the level below, level by level, until a single top-tier server is
+// left. A larger fan-in gives a shallower tree with fewer hops but more
+// inputs per server. Packets are labelled with the number of values they
+// ship, which shows which aggregates decompose cheaply.
+//
+// MiddleLayerResults holds the results of every mid-tier level, starting
+// with the one above the splits. Level is the mid-tier level being sent to
+// or merged.
+type GROUPBY1 struct {
+	grouping
+	aggregation
+
+	OrderMachines [][]Order
+
+	BottomLayerResults [][]AggregationResult
+	MiddleLayerResults [][][]AggregationResult
+	TopLayerResult     []AggregationResult
+	Level              int
+
+	dataset  *Dataset
+	topology Topology
+}
+
+// defaultFanIn is the number of servers of the level below merged by one
+// aggregation server unless the topology sets it.
+const defaultFanIn = 2
+
+func (s *GROUPBY1) Name() string {
+	return "GROUPBY1"
+}
+
+func (s *GROUPBY1) Description() string {
+	return "GROUP BY merged through a tree of aggregation tiers"
+}
+
+func (s *GROUPBY1) UseDataset(d *Dataset) error {
+	s.dataset = d
+	return nil
+}
+
+func (s *GROUPBY1) SetTopology(t Topology) error {
+	if err := checkTopology("GROUPBY1", t, TableOrders, TableMidTier); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+// MidTierTable returns the name of the table of mid-tier level level.
+func MidTierTable(level int) string {
+	if level == 0 {
+		return TableMidTier
+	}
+	return fmt.Sprintf("%s%d", TableMidTier, level+1)
+}
+
+// FanIn returns the number of servers of the level below that one
+// aggregation server merges.
+func (s *GROUPBY1) FanIn() int {
+	return s.topology.withDefaults(Topology{FanIn: defaultFanIn}).FanIn
+}
+
+// MidLevels returns the number of servers of every mid-tier level, starting
+// with the one above the splits. There are none if the top tier can merge
+// all splits itself.
+func (s *GROUPBY1) MidLevels() []int {
+	var levels []int
+	n := len(s.OrderMachines)
+	for n > s.FanIn() {
+		n = (n + s.FanIn() - 1) / s.FanIn()
+		levels = append(levels, n)
+	}
+	return levels
+}
+
+// Splits returns the range of splits below server m of mid-tier level
+// level.
+func (s *GROUPBY1) Splits(level, m int) (from, to int) {
+	span := s.FanIn()
+	for range level {
+		span *= s.FanIn()
+	}
+	return m * span, min((m+1)*span, len(s.OrderMachines))
+}
+
+// inputs returns the results that mid-tier level level merges. The level
+// after the last mid-tier level is the top tier.
+func (s *GROUPBY1) inputs(level int) [][]AggregationResult {
+	if level == 0 {
+		return s.BottomLayerResults
+	}
+	return s.MiddleLayerResults[level-1]
+}
+
+// endpoint returns server i of mid-tier level level, of the splits for
+// level -1 and of the top tier after the last mid-tier level.
+func (s *GROUPBY1) endpoint(level, i int) Endpoint {
+	switch {
+	case level < 0:
+		return Endpoint{Table: TableOrders, Split: i}
+	case level == len(s.MiddleLayerResults):
+		return Endpoint{Table: TableTopTier}
+	}
+	return Endpoint{Table: MidTierTable(level), Split: i}
+}
+
+func (s *GROUPBY1) Setup(e *Engine, rng *rand.Rand) {
+	e.PacketTicks = 60 // Slower speed
+	if s.dataset != nil {
+		s.OrderMachines = s.dataset.Orders
+		e.Schema = s.dataset.Schema
+		return
+	}
+
+	items := []string{"Apple", "Banana", "Cherry"}
+	t := s.topology.withDefaults(Topology{Orders: 4})
+	s.OrderMachines = splitEvenly(newItemOrders(rng, items, ordersPerSplit*t.Orders), t.Orders)
+}
+
+func (s *GROUPBY1) Reset(e *Engine) Step {
+	s.BottomLayerResults = make([][]AggregationResult, len(s.OrderMachines))
+	s.MiddleLayerResults = nil
+	for _, n := range s.MidLevels() {
+		s.MiddleLayerResults = append(s.MiddleLayerResults, make([][]AggregationResult, n))
+	}
+	s.TopLayerResult = []AggregationResult{}
+	s.Level = 0
+	return StepGroupByBottomLayer
+}
+
+func (s *GROUPBY1) Update(e *Engine) {
+	switch e.Step {
+	case StepGroupByBottomLayer:
+		for i := range s.OrderMachines {
+			result := groups{}
+			for _, order := range s.OrderMachines[i] {
+				result.get(s.key(order)).add(order)
+			}
+			s.BottomLayerResults[i] = result.results()
+		}
+		s.nextLevel(e)
+	case StepSendToMiddleLayer:
+		s.sendUp(e, s.Level)
+		e.Step = StepRespondingToMiddleLayer
+	case StepRespondingToMiddleLayer:
+		if s.arrived(e, len(s.inputs(s.Level))) {
+			e.hold(StepPauseBeforeGroupByMiddleLayer, mergePause, StepGroupByMiddleLayer)
+		}
+	case StepGroupByMiddleLayer:
+		// Merge results in the current mid-tier level
+		inputs := s.inputs(s.Level)
+		for m := range s.MiddleLayerResults[s.Level] {
+			result := groups{}
+			for i := m * s.FanIn(); i < (m+1)*s.FanIn() && i < len(inputs); i++ {
+				for _, res := range inputs[i] {
+					result.get(res.Key).merge(res.Partial)
+				}
+			}
+			s.MiddleLayerResults[s.Level][m] = result.results()
+		}
+		s.Level++
+		s.nextLevel(e)
+	case StepSendToTopLayer:
+		s.sendUp(e, s.Level)
+		e.Step = StepRespondingToTopLayer
+	case StepRespondingToTopLayer:
+		if s.arrived(e, len(s.inputs(s.Level))) {
+			e.hold(StepPauseBeforeGroupByTopLayer, mergePause, StepGroupByTopLayer)
+		}
+	case StepGroupByTopLayer:
+		// Merge results in top layer
+		result := groups{}
+		for _, results := range s.inputs(s.Level) {
+			for _, res := range results {
+				result.get(res.Key).merge(res.Partial)
+			}
+		}
+		s.TopLayerResult = result.results()
+		e.Step = StepFinished
+	case StepFinished:
+		e.pauseBeforeRestart()
+	}
+}
+
+// nextLevel moves on to sending the results of the level below s.Level up,
+// to the top tier once every mid-tier level has merged.
+func (s *GROUPBY1) nextLevel(e *Engine) {
+	if s.Level < len(s.MiddleLayerResults) {
+		e.hold(StepPauseBeforeSendToMiddleLayer, sendPause, StepSendToMiddleLayer)
+	} else {
+		e.hold(StepPauseBeforeSendToTopLayer, sendPause, StepSendToTopLayer)
+	}
+}
+
+// sendUp sends the results of the level below level to the servers of level
+// that merge them.
+func (s *GROUPBY1) sendUp(e *Engine, level int) {
+	inputs := s.inputs(level)
+	for i, results := range inputs {
+		e.place(i, s.endpoint(level-1, i))
+		e.send(i, s.endpoint(level, i/s.FanIn()))
+		e.Packets[i].Payload = s.shipped(results)
+	}
+	for i := len(inputs); i < len(e.Packets); i++ {
+		e.Packets[i].Active = false
+	}
+}
+
+// arrived moves the first n packets and reports whether all have arrived.
+func (s *GROUPBY1) arrived(e *Engine, n int) bool {
+	packetsFinished := 0
+	for i := 0; i < n; i++ {
+		if e.Packets[i].Active {
+			if e.movePacket(i) {
+				e.Packets[i].Active = false
+				packetsFinished++
+			}
+		} else {
+			packetsFinished++
+		}
+	}
+	return packetsFinished == n
+}
+
+// shipped labels a packet with the number of values it ships for results.
+func (s *GROUPBY1) shipped(results []AggregationResult) string {
+	n := 0
+	for _, res := range results {
+		n += s.Aggregate().StateSize(res.Partial)
+	}
+	return fmt.Sprintf("%d values", n)
+}
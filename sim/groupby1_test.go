@@ -0,0 +1,61 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestGROUPBY1Tree(t *testing.T) {
+	tests := []struct {
+		splits, fanIn int
+		levels        []int
+		// ranges is the range of splits below every server of every
+		// mid-tier level.
+		ranges [][][2]int
+	}{
+		{splits: 1, fanIn: 2},
+		{splits: 3, fanIn: 3},
+		// A fan-in of 0 is the default of 2.
+		{splits: 4, fanIn: 0, levels: []int{2}, ranges: [][][2]int{
+			{{0, 2}, {2, 4}},
+		}},
+		{splits: 7, fanIn: 3, levels: []int{3}, ranges: [][][2]int{
+			{{0, 3}, {3, 6}, {6, 7}},
+		}},
+		{splits: 8, fanIn: 2, levels: []int{4, 2}, ranges: [][][2]int{
+			{{0, 2}, {2, 4}, {4, 6}, {6, 8}},
+			{{0, 4}, {4, 8}},
+		}},
+		{splits: 10, fanIn: 3, levels: []int{4, 2}, ranges: [][][2]int{
+			{{0, 3}, {3, 6}, {6, 9}, {9, 10}},
+			{{0, 9}, {9, 10}},
+		}},
+		{splits: 16, fanIn: 4, levels: []int{4}, ranges: [][][2]int{
+			{{0, 4}, {4, 8}, {8, 12}, {12, 16}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d splits/fan-in %d", tt.splits, tt.fanIn), func(t *testing.T) {
+			s := &GROUPBY1{}
+			if err := s.SetTopology(Topology{Orders: tt.splits, FanIn: tt.fanIn}); err != nil {
+				t.Fatal(err)
+			}
+			NewEngine(s, rand.New(rand.NewSource(1)))
+			if got := s.MidLevels(); !reflect.DeepEqual(got, tt.levels) {
+				t.Fatalf("MidLevels() = %v, want %v", got, tt.levels)
+			}
+			for level, n := range tt.levels {
+				var got [][2]int
+				for m := range n {
+					from, to := s.Splits(level, m)
+					got = append(got, [2]int{from, to})
+				}
+				if !reflect.DeepEqual(got, tt.ranges[level]) {
+					t.Errorf("level %d: splits %v, want %v", level, got, tt.ranges[level])
+				}
+			}
+		})
+	}
+}
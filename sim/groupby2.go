@@ -0,0 +1,124 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Steps of GROUPBY2.
+const (
+	StepParallelAggregation Step = FirstScenarioStep + iota
+	StepG2PauseBeforeRestart
+)
+
+// GROUPBY2 aggregates over splits that are sorted by the GROUP BY columns,
+// so every group is aggregated in parallel while its rows are scanned.
+//
+// GroupLocations and ParallelAggregations hold the rows in OrderMachines and
+// the running state of every group, in key order.
+type GROUPBY2 struct {
+	grouping
+	aggregation
+
+	OrderMachines [][]Order
+	AllOrders     []Order
+
+	GroupLocations       [][]Cursor
+	ParallelScanIndex    int
+	ParallelAggregations []AggregationResult
+	TopLayerResult       []AggregationResult
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (s *GROUPBY2) Name() string {
+	return "GROUPBY2"
+}
+
+func (s *GROUPBY2) Description() string {
+	return "GROUP BY over splits sorted by the GROUP BY columns"
+}
+
+func (s *GROUPBY2) UseDataset(d *Dataset) error {
+	if n := len(d.allOrders()); n < len(d.Orders) {
+		return fmt.Errorf("GROUPBY2 needs at least as many %s rows as splits, got %d rows for %d splits", d.Schema.OrderTable, n, len(d.Orders))
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *GROUPBY2) SetTopology(t Topology) error {
+	if err := checkTopology("GROUPBY2", t, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *GROUPBY2) Setup(e *Engine, rng *rand.Rand) {
+	e.PacketTicks = 60
+
+	var allOrders []Order
+	splits := s.topology.withDefaults(Topology{Orders: 4}).Orders
+	if s.dataset != nil {
+		allOrders = s.dataset.allOrders()
+		splits = len(s.dataset.Orders)
+		e.Schema = s.dataset.Schema
+	} else {
+		items := []string{"Apple", "Banana", "Cherry", "Grape", "Orange"}
+
+		allOrders = newItemOrders(rng, items, ordersPerSplit*splits)
+	}
+
+	s.sortByKey(allOrders)
+
+	// Distribute sorted orders evenly into the machines
+	s.OrderMachines = splitEvenly(allOrders, splits)
+	s.AllOrders = allOrders
+}
+
+func (s *GROUPBY2) Reset(e *Engine) Step {
+	s.GroupLocations = nil
+	s.ParallelAggregations = nil
+	for i, machine := range s.OrderMachines {
+		for j, order := range machine {
+			// The rows of a group are adjacent.
+			key := s.key(order)
+			if n := len(s.ParallelAggregations); n == 0 || !sameKey(s.ParallelAggregations[n-1].Key, key) {
+				s.ParallelAggregations = append(s.ParallelAggregations, AggregationResult{Key: key})
+				s.GroupLocations = append(s.GroupLocations, nil)
+			}
+			g := len(s.GroupLocations) - 1
+			s.GroupLocations[g] = append(s.GroupLocations[g], Cursor{Split: i, Row: j})
+		}
+	}
+	s.ParallelScanIndex = 0
+	s.TopLayerResult = []AggregationResult{}
+	return StepParallelAggregation
+}
+
+func (s *GROUPBY2) Update(e *Engine) {
+	if e.Step == StepParallelAggregation && e.scanDue() {
+		// Update aggregations for all groups at the current scan index
+		groupsFinished := 0
+		for g, locations := range s.GroupLocations {
+			if s.ParallelScanIndex < len(locations) {
+				loc := locations[s.ParallelScanIndex]
+				order := s.OrderMachines[loc.Split][loc.Row]
+				s.ParallelAggregations[g].add(order)
+			} else {
+				groupsFinished++
+			}
+		}
+
+		s.ParallelScanIndex++
+
+		// Check if all groups are done
+		if groupsFinished == len(s.GroupLocations) {
+			// Transfer final results to TopLayerResult for display
+			s.TopLayerResult = s.ParallelAggregations
+			e.hold(StepG2PauseBeforeRestart, restartPause, StepRestart)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// StepAggregating is the step in which GROUPBY3 reads the next row.
+const StepAggregating = FirstScenarioStep
+
+// GROUPBY3 runs the two GROUP BY algorithms side by side on the same rows.
+// The hash aggregate reads the rows in OrderID order and keeps a hash table
+// entry for every group it has seen, emitting nothing until the input ends.
+// The stream aggregate reads the same rows sorted by the GROUP BY columns, so
+// it only keeps the current group and emits it as soon as the key changes.
+type GROUPBY3 struct {
+	grouping
+	aggregation
+
+	Orders       []Order
+	SortedOrders []Order
+
+	// ScanIndex is the next row of both inputs; both sides read one row per
+	// scan.
+	ScanIndex int
+
+	// HashTable holds the running totals in the order the groups were
+	// first seen and HashUpdated the entry the last row went to, or -1.
+	// HashResult is only set once the input has ended.
+	HashTable   []AggregationResult
+	HashUpdated int
+	HashResult  []AggregationResult
+
+	// StreamGroup is the group being aggregated, if StreamOpen, and
+	// StreamResult the groups emitted so far.
+	StreamGroup  AggregationResult
+	StreamOpen   bool
+	StreamResult []AggregationResult
+
+	dataset *Dataset
+}
+
+// groupby3Rows is the number of orders generated for GROUPBY3.
+const groupby3Rows = 20
+
+func (s *GROUPBY3) Name() string {
+	return "GROUPBY3"
+}
+
+func (s *GROUPBY3) Description() string {
+	return "Hash aggregate and stream aggregate side by side"
+}
+
+func (s *GROUPBY3) UseDataset(d *Dataset) error {
+	if len(d.allOrders()) == 0 {
+		return fmt.Errorf("GROUPBY3 needs %s rows to aggregate", d.Schema.OrderTable)
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *GROUPBY3) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	if s.dataset != nil {
+		s.Orders = s.dataset.allOrders()
+		e.Schema = s.dataset.Schema
+	} else {
+		items := []string{"Apple", "Banana", "Cherry", "Grape", "Orange"}
+		s.Orders = newItemOrders(rng, items, groupby3Rows)
+	}
+
+	s.SortedOrders = append([]Order(nil), s.Orders...)
+	s.sortByKey(s.SortedOrders)
+}
+
+func (s *GROUPBY3) Reset(e *Engine) Step {
+	s.ScanIndex = 0
+	s.HashTable = nil
+	s.HashUpdated = -1
+	s.HashResult = nil
+	s.StreamOpen = false
+	s.StreamResult = nil
+	return StepAggregating
+}
+
+func (s *GROUPBY3) Update(e *Engine) {
+	if e.Step != StepAggregating || !e.scanDue() {
+		return
+	}
+	if s.ScanIndex >= len(s.Orders) {
+		// The hash table can only be emitted now, the last stream group is
+		// closed by the end of the input.
+		s.HashResult = append([]AggregationResult(nil), s.HashTable...)
+		sort.Slice(s.HashResult, func(i, j int) bool { return keyLess(s.HashResult[i].Key, s.HashResult[j].Key) })
+		s.emitStreamGroup()
+		e.pauseBeforeRestart()
+		return
+	}
+
+	// Hash aggregate: find or insert the entry of the key.
+	o := s.Orders[s.ScanIndex]
+	key := s.key(o)
+	s.HashUpdated = -1
+	for i := range s.HashTable {
+		if sameKey(s.HashTable[i].Key, key) {
+			s.HashUpdated = i
+			break
+		}
+	}
+	if s.HashUpdated < 0 {
+		s.HashUpdated = len(s.HashTable)
+		s.HashTable = append(s.HashTable, AggregationResult{Key: key})
+	}
+	s.HashTable[s.HashUpdated].add(o)
+
+	// Stream aggregate: a new key closes the current group.
+	o = s.SortedOrders[s.ScanIndex]
+	key = s.key(o)
+	if s.StreamOpen && !sameKey(s.StreamGroup.Key, key) {
+		s.emitStreamGroup()
+	}
+	if !s.StreamOpen {
+		s.StreamGroup = AggregationResult{Key: key}
+		s.StreamOpen = true
+	}
+	s.StreamGroup.add(o)
+
+	s.ScanIndex++
+}
+
+// emitStreamGroup closes the current group of the stream aggregate.
+func (s *GROUPBY3) emitStreamGroup() {
+	if s.StreamOpen {
+		s.StreamResult = append(s.StreamResult, s.StreamGroup)
+		s.StreamOpen = false
+	}
+}
@@ -0,0 +1,180 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// TopK is the LIMIT of GROUPBY4 and HavingMinSum the smallest SUM(Price) its
+// HAVING clause keeps.
+const (
+	TopK         = 3
+	HavingMinSum = 800
+)
+
+// itemsPerSplit is the number of Items GROUPBY4 generates per split.
+const itemsPerSplit = 6
+
+// GROUPBY4 runs
+//
+//	SELECT Item, SUM(Price) FROM Orders GROUP BY Item
+//	HAVING SUM(Price) >= HavingMinSum ORDER BY SUM(Price) DESC LIMIT TopK
+//
+// through the tiers of GROUPBY1, with Item replaced by the GROUP BY columns
+// if they are set. The Orders are split by their key, so every group is
+// complete on one split and the splits can apply HAVING and LIMIT before
+// shipping anything. Every tier then ships at most TopK rows.
+//
+// LocalGroups, MiddleReceived and TopReceived hold the rows a server has,
+// ordered by SUM(Price); BottomLayerResults, MiddleLayerResults and
+// TopLayerResult the prefix of them the server ships.
+type GROUPBY4 struct {
+	GROUPBY1
+
+	LocalGroups    [][]AggregationResult
+	MiddleReceived [][][]AggregationResult
+	TopReceived    []AggregationResult
+}
+
+func (s *GROUPBY4) Name() string {
+	return "GROUPBY4"
+}
+
+func (s *GROUPBY4) Description() string {
+	return "GROUP BY with HAVING, ORDER BY and LIMIT pushed down to every tier"
+}
+
+func (s *GROUPBY4) SetTopology(t Topology) error {
+	if err := checkTopology("GROUPBY4", t, TableOrders, TableMidTier); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+// SetAggregate accepts only SUM, which HAVING and ORDER BY rank the groups
+// by.
+func (s *GROUPBY4) SetAggregate(a Aggregate) error {
+	if a != AggSum {
+		return fmt.Errorf("GROUPBY4 supports only %s, not %s", AggSum, a)
+	}
+	return s.GROUPBY1.SetAggregate(a)
+}
+
+func (s *GROUPBY4) Setup(e *Engine, rng *rand.Rand) {
+	e.PacketTicks = 60
+	if s.dataset != nil {
+		s.OrderMachines = s.splitByKey(s.dataset.allOrders(), len(s.dataset.Orders))
+		e.Schema = s.dataset.Schema
+		return
+	}
+
+	t := s.topology.withDefaults(Topology{Orders: 4})
+	items := make([]string, itemsPerSplit*t.Orders)
+	for i := range items {
+		items[i] = fmt.Sprintf("Item%02d", i+1)
+	}
+	s.OrderMachines = s.splitByKey(newItemOrders(rng, items, ordersPerSplit*t.Orders), t.Orders)
+}
+
+// splitByKey places all orders of a group on the same one of n splits,
+// dealing the groups out in key order.
+func (s *GROUPBY4) splitByKey(orders []Order, n int) [][]Order {
+	var keys [][]string
+	split := map[string]int{}
+	for _, o := range orders {
+		key := s.key(o)
+		if _, ok := split[keyString(key)]; !ok {
+			split[keyString(key)] = 0
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	for i, key := range keys {
+		split[keyString(key)] = i % n
+	}
+
+	splits := make([][]Order, n)
+	for _, o := range orders {
+		i := split[keyString(s.key(o))]
+		splits[i] = append(splits[i], o)
+	}
+	return splits
+}
+
+// byTotal orders results by SUM(Price), largest first.
+func byTotal(results []AggregationResult) []AggregationResult {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Sum != results[j].Sum {
+			return results[i].Sum > results[j].Sum
+		}
+		return keyLess(results[i].Key, results[j].Key)
+	})
+	return results
+}
+
+// limit returns the first TopK of results.
+func limit(results []AggregationResult) []AggregationResult {
+	if len(results) > TopK {
+		return results[:TopK]
+	}
+	return results
+}
+
+func (s *GROUPBY4) Reset(e *Engine) Step {
+	s.LocalGroups = make([][]AggregationResult, len(s.OrderMachines))
+	s.MiddleReceived = nil
+	for _, n := range s.MidLevels() {
+		s.MiddleReceived = append(s.MiddleReceived, make([][]AggregationResult, n))
+	}
+	s.TopReceived = nil
+	return s.GROUPBY1.Reset(e)
+}
+
+func (s *GROUPBY4) Update(e *Engine) {
+	switch e.Step {
+	case StepGroupByBottomLayer:
+		for i := range s.OrderMachines {
+			result := groups{}
+			for _, order := range s.OrderMachines[i] {
+				result.get(s.key(order)).add(order)
+			}
+			s.LocalGroups[i] = byTotal(result.results())
+			// HAVING keeps a prefix of the groups ordered by SUM(Price).
+			n := 0
+			for n < len(s.LocalGroups[i]) && s.LocalGroups[i][n].Sum >= HavingMinSum {
+				n++
+			}
+			s.BottomLayerResults[i] = limit(s.LocalGroups[i][:n])
+		}
+		s.nextLevel(e)
+	case StepSendToMiddleLayer, StepSendToTopLayer:
+		s.GROUPBY1.Update(e)
+		for i, results := range s.inputs(s.Level) {
+			e.Packets[i].Payload = fmt.Sprintf("%d rows", len(results))
+		}
+	case StepGroupByMiddleLayer:
+		inputs := s.inputs(s.Level)
+		for m := range s.MiddleLayerResults[s.Level] {
+			var received []AggregationResult
+			for i := m * s.FanIn(); i < (m+1)*s.FanIn() && i < len(inputs); i++ {
+				received = append(received, inputs[i]...)
+			}
+			s.MiddleReceived[s.Level][m] = byTotal(received)
+			s.MiddleLayerResults[s.Level][m] = limit(s.MiddleReceived[s.Level][m])
+		}
+		s.Level++
+		s.nextLevel(e)
+	case StepGroupByTopLayer:
+		var received []AggregationResult
+		for _, results := range s.inputs(s.Level) {
+			received = append(received, results...)
+		}
+		s.TopReceived = byTotal(received)
+		s.TopLayerResult = limit(s.TopReceived)
+		e.Step = StepFinished
+	default:
+		s.GROUPBY1.Update(e)
+	}
+}
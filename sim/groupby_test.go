@@ -0,0 +1,229 @@
+package sim
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// groupByDirect computes agg over every group of orders without any tiers.
+func groupByDirect(orders []Order, columns []Column, agg Aggregate) map[string]float64 {
+	prices := map[string][]int{}
+	users := map[string]map[int]bool{}
+	for _, o := range orders {
+		var key []string
+		for _, c := range columns {
+			key = append(key, c.Value(o))
+		}
+		k := keyString(key)
+		prices[k] = append(prices[k], o.Price)
+		if users[k] == nil {
+			users[k] = map[int]bool{}
+		}
+		users[k][o.UserID] = true
+	}
+	values := map[string]float64{}
+	for k, p := range prices {
+		sum := 0
+		for _, price := range p {
+			sum += price
+		}
+		switch agg {
+		case AggSum:
+			values[k] = float64(sum)
+		case AggCount:
+			values[k] = float64(len(p))
+		case AggAvg:
+			values[k] = float64(sum) / float64(len(p))
+		case AggMin:
+			values[k] = float64(slices.Min(p))
+		case AggMax:
+			values[k] = float64(slices.Max(p))
+		case AggCountDistinct:
+			values[k] = float64(len(users[k]))
+		}
+	}
+	return values
+}
+
+// columnsName names columns for a subtest.
+func columnsName(columns []Column) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name(DefaultSchema)
+	}
+	return strings.Join(names, ",")
+}
+
+// runGroupBy runs s until its result is shown in step done.
+func runGroupBy(t *testing.T, s Scenario, seed int64, done Step) *Engine {
+	t.Helper()
+	e := NewEngine(s, rand.New(rand.NewSource(seed)))
+	e.Start()
+	for ticks := 0; e.Step != done; ticks++ {
+		if ticks == 100000 {
+			t.Fatalf("%s did not finish", s.Name())
+		}
+		e.Tick()
+	}
+	return e
+}
+
+func TestAggregates(t *testing.T) {
+	type scenario interface {
+		AggregateScenario
+		GroupByScenario
+	}
+	tests := []struct {
+		name    string
+		new     func() scenario
+		done    Step
+		orders  func(s scenario) []Order
+		results func(s scenario) [][]AggregationResult
+	}{
+		{
+			name: "GROUPBY1",
+			new:  func() scenario { return &GROUPBY1{} },
+			done: StepPauseBeforeRestart,
+			orders: func(s scenario) []Order {
+				return slices.Concat(s.(*GROUPBY1).OrderMachines...)
+			},
+			results: func(s scenario) [][]AggregationResult {
+				return [][]AggregationResult{s.(*GROUPBY1).TopLayerResult}
+			},
+		},
+		{
+			name: "GROUPBY1/7 splits/fan-in 3",
+			new: func() scenario {
+				s := &GROUPBY1{}
+				if err := s.SetTopology(Topology{Orders: 7, FanIn: 3}); err != nil {
+					panic(err)
+				}
+				return s
+			},
+			done: StepPauseBeforeRestart,
+			orders: func(s scenario) []Order {
+				return slices.Concat(s.(*GROUPBY1).OrderMachines...)
+			},
+			results: func(s scenario) [][]AggregationResult {
+				return [][]AggregationResult{s.(*GROUPBY1).TopLayerResult}
+			},
+		},
+		{
+			name: "GROUPBY2",
+			new:  func() scenario { return &GROUPBY2{} },
+			done: StepG2PauseBeforeRestart,
+			orders: func(s scenario) []Order {
+				return s.(*GROUPBY2).AllOrders
+			},
+			results: func(s scenario) [][]AggregationResult {
+				return [][]AggregationResult{s.(*GROUPBY2).TopLayerResult}
+			},
+		},
+		{
+			name: "GROUPBY3",
+			new:  func() scenario { return &GROUPBY3{} },
+			done: StepPauseBeforeRestart,
+			orders: func(s scenario) []Order {
+				return s.(*GROUPBY3).Orders
+			},
+			results: func(s scenario) [][]AggregationResult {
+				return [][]AggregationResult{s.(*GROUPBY3).HashResult, s.(*GROUPBY3).StreamResult}
+			},
+		},
+	}
+	groupings := [][]Column{{ColumnItem}, {ColumnItem, ColumnUserID}}
+	aggregates := []Aggregate{AggSum, AggCount, AggAvg, AggMin, AggMax, AggCountDistinct}
+	for _, tt := range tests {
+		for _, columns := range groupings {
+			for _, agg := range aggregates {
+				t.Run(fmt.Sprintf("%s/%s/%v", tt.name, columnsName(columns), agg), func(t *testing.T) {
+					s := tt.new()
+					if err := s.SetGroupBy(columns); err != nil {
+						t.Fatal(err)
+					}
+					if err := s.SetAggregate(agg); err != nil {
+						t.Fatal(err)
+					}
+					runGroupBy(t, s, 1, tt.done)
+					want := groupByDirect(tt.orders(s), columns, agg)
+					for _, results := range tt.results(s) {
+						got := map[string]float64{}
+						for _, res := range results {
+							got[keyString(res.Key)] = agg.Value(res.Partial)
+						}
+						if !maps.Equal(got, want) {
+							t.Errorf("got %v, want %v", got, want)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestGROUPBY4(t *testing.T) {
+	topologies := []Topology{
+		{},
+		{Orders: 1},
+		{Orders: 2},
+		{Orders: 7, FanIn: 3},
+		{Orders: 8, FanIn: 2},
+		{Orders: 16, FanIn: 4},
+	}
+	groupings := [][]Column{{ColumnItem}, {ColumnUserID}}
+	for _, topology := range topologies {
+		for _, columns := range groupings {
+			for seed := int64(1); seed <= 3; seed++ {
+				name := fmt.Sprintf("%d splits/fan-in %d/%s/seed%d", topology.Orders, topology.FanIn, columnsName(columns), seed)
+				t.Run(name, func(t *testing.T) {
+					s := &GROUPBY4{}
+					if err := s.SetTopology(topology); err != nil {
+						t.Fatal(err)
+					}
+					if err := s.SetGroupBy(columns); err != nil {
+						t.Fatal(err)
+					}
+					runGroupBy(t, s, seed, StepPauseBeforeRestart)
+
+					// HAVING, ORDER BY and LIMIT over all orders at once.
+					var want []string
+					var kept []AggregationResult
+					for k, sum := range groupByDirect(slices.Concat(s.OrderMachines...), columns, AggSum) {
+						if sum >= HavingMinSum {
+							kept = append(kept, AggregationResult{Key: strings.Split(k, "\x00"), Partial: Partial{Sum: int(sum)}})
+						}
+					}
+					for _, res := range limit(byTotal(kept)) {
+						want = append(want, fmt.Sprintf("%s:%d", strings.Join(res.Key, ","), res.Sum))
+					}
+					var got []string
+					for _, res := range s.TopLayerResult {
+						got = append(got, fmt.Sprintf("%s:%d", strings.Join(res.Key, ","), res.Sum))
+					}
+					if !slices.Equal(got, want) {
+						t.Errorf("got %v, want %v", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestGROUPBY4Aggregate(t *testing.T) {
+	s := &GROUPBY4{}
+	if err := s.SetAggregate(AggSum); err != nil {
+		t.Errorf("SetAggregate(SUM) = %v", err)
+	}
+	for _, agg := range []Aggregate{AggCount, AggAvg, AggMin, AggMax, AggCountDistinct} {
+		if err := s.SetAggregate(agg); err == nil {
+			t.Errorf("SetAggregate(%v) succeeded", agg)
+		}
+	}
+	if s.Aggregate() != AggSum {
+		t.Errorf("aggregate is %v after rejected SetAggregate calls, want SUM", s.Aggregate())
+	}
+}
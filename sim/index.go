@@ -0,0 +1,122 @@
+package sim
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// indexTables are the tables of the JOINs through a secondary index on
+// Orders(UserID). The index is sorted by UserID and split evenly.
+type indexTables struct {
+	UserMachines  [][]User
+	OrderMachines [][]Order
+	IndexMachines [][]IndexEntry
+
+	// storing copies Item and Price into the index entries, like
+	// STORING (Item, Price).
+	storing bool
+
+	// interleaved stores every index entry in the split of its user, like
+	// INTERLEAVE IN Users, instead of splitting the index evenly.
+	interleaved bool
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (t *indexTables) useDataset(scenario string, d *Dataset) error {
+	if err := d.needUsers(scenario); err != nil {
+		return err
+	}
+	t.dataset = d
+	return nil
+}
+
+func (t *indexTables) setTopology(scenario string, topology Topology) error {
+	if err := checkTopology(scenario, topology, TableUsers, TableOrders, TableIndex); err != nil {
+		return err
+	}
+	t.topology = topology
+	return nil
+}
+
+// setup fills the tables from the dataset or generates them.
+func (t *indexTables) setup(e *Engine, rng *rand.Rand) {
+	topology := t.topology.withDefaults(Topology{Users: 2, Orders: 2, Index: 2})
+	var orders []Order
+	if t.dataset != nil {
+		t.UserMachines = t.dataset.Users
+		t.OrderMachines = t.dataset.Orders
+		e.Schema = t.dataset.Schema
+		orders = t.dataset.allOrders()
+	} else {
+		t.UserMachines = newUserMachines(topology.Users)
+		t.OrderMachines = newOrderMachines(rng, topology.Users*usersPerSplit, topology.Orders)
+		rows := 0
+		for _, machine := range t.OrderMachines {
+			rows += len(machine)
+		}
+		// Take the machines' rows in turn.
+		for i := 0; len(orders) < rows; i++ {
+			for _, machine := range t.OrderMachines {
+				if i < len(machine) {
+					orders = append(orders, machine[i])
+				}
+			}
+		}
+	}
+
+	index := make([]IndexEntry, len(orders))
+	for i, o := range orders {
+		index[i] = IndexEntry{UserID: o.UserID, OrderID: o.OrderID}
+		if t.storing {
+			index[i].Item, index[i].Price = o.Item, o.Price
+		}
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].UserID < index[j].UserID })
+	if !t.interleaved {
+		t.IndexMachines = splitEvenly(index, topology.Index)
+		return
+	}
+	split := userSplits(t.UserMachines)
+	t.IndexMachines = make([][]IndexEntry, len(t.UserMachines))
+	for _, entry := range index {
+		t.IndexMachines[split[entry.UserID]] = append(t.IndexMachines[split[entry.UserID]], entry)
+	}
+}
+
+// lookupIndex seeks the index to userID. It returns the positions of all
+// entries of userID, which follow each other, and where the seek stopped: the
+// first of them or, if there are none, the entry after userID.
+func (t *indexTables) lookupIndex(userID int) (Cursor, []Cursor) {
+	seek := Cursor{Split: -1, Row: -1}
+	var entries []Cursor
+scan:
+	for i, machine := range t.IndexMachines {
+		for j, entry := range machine {
+			seek = Cursor{Split: i, Row: j}
+			switch {
+			case entry.UserID == userID:
+				entries = append(entries, seek)
+			case entry.UserID > userID:
+				break scan
+			}
+		}
+	}
+	if len(entries) > 0 {
+		seek = entries[0]
+	}
+	return seek, entries
+}
+
+// lookupOrder returns the position of the order with orderID.
+func (t *indexTables) lookupOrder(orderID int) Cursor {
+	for i, machine := range t.OrderMachines {
+		for j, order := range machine {
+			if order.OrderID == orderID {
+				return Cursor{Split: i, Row: j}
+			}
+		}
+	}
+	return Cursor{Split: -1, Row: -1}
+}
@@ -0,0 +1,137 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// JoinType selects the rows a JOIN returns.
+type JoinType int
+
+const (
+	// InnerJoin returns every matching pair of User and Order.
+	InnerJoin JoinType = iota
+	// LeftOuterJoin also returns the users without orders, padded with NULLs.
+	LeftOuterJoin
+	// SemiJoin returns every user that has an order once, like EXISTS.
+	SemiJoin
+	// AntiJoin returns the users without orders, like NOT EXISTS.
+	AntiJoin
+)
+
+var joinTypeNames = []string{"INNER", "LEFT OUTER", "SEMI", "ANTI"}
+
+func (t JoinType) String() string {
+	if t < 0 || int(t) >= len(joinTypeNames) {
+		return fmt.Sprintf("JoinType(%d)", int(t))
+	}
+	return joinTypeNames[t]
+}
+
+// ParseJoinType parses "inner", "left", "semi" or "anti".
+func ParseJoinType(s string) (JoinType, error) {
+	switch strings.ToLower(s) {
+	case "inner":
+		return InnerJoin, nil
+	case "left", "left-outer":
+		return LeftOuterJoin, nil
+	case "semi":
+		return SemiJoin, nil
+	case "anti":
+		return AntiJoin, nil
+	}
+	return InnerJoin, fmt.Errorf("unknown join type %q, want inner, left, semi or anti", s)
+}
+
+// joinMatch records that order o matches user u. first is set for the first
+// match of u, so that a SEMI JOIN returns every user once.
+func (e *Engine) joinMatch(u User, o Order, first bool) {
+	switch e.JoinType {
+	case InnerJoin, LeftOuterJoin:
+		e.Joined = append(e.Joined, JoinedData{User: u, Order: o})
+	case SemiJoin:
+		if first {
+			e.Joined = append(e.Joined, JoinedData{User: u})
+		}
+	}
+}
+
+// joinDone records that every order of user u has been seen and whether any
+// of them matched.
+func (e *Engine) joinDone(u User, matched bool) {
+	if matched {
+		return
+	}
+	switch e.JoinType {
+	case LeftOuterJoin:
+		e.Joined = append(e.Joined, JoinedData{User: u, Null: true})
+	case AntiJoin:
+		e.Joined = append(e.Joined, JoinedData{User: u})
+	}
+}
+
+// joinUser records user u together with all of its matching orders.
+func (e *Engine) joinUser(u User, matches ...Order) {
+	for i, o := range matches {
+		e.joinMatch(u, o, i == 0)
+	}
+	e.joinDone(u, len(matches) > 0)
+}
+
+// userNames names the first generated users.
+var userNames = []string{"Alice", "Bob", "Charlie", "David", "Eve", "Frank", "Grace", "Heidi", "Ivan", "Judy"}
+
+// usersPerSplit is the number of users generated for every User split.
+const usersPerSplit = 5
+
+// newUserMachines returns the User table split over n machines in UserID
+// order.
+func newUserMachines(n int) [][]User {
+	userMachines := make([][]User, n)
+	for i := range userMachines {
+		userMachines[i] = make([]User, usersPerSplit)
+		for j := range userMachines[i] {
+			id := i*usersPerSplit + j + 1
+			name := fmt.Sprintf("User%d", id)
+			if id <= len(userNames) {
+				name = userNames[id-1]
+			}
+			userMachines[i][j] = User{UserID: id, Name: name}
+		}
+	}
+	return userMachines
+}
+
+// userSplits maps every UserID to the split that holds the user.
+func userSplits(userMachines [][]User) map[int]int {
+	split := map[int]int{}
+	for i, machine := range userMachines {
+		for _, u := range machine {
+			split[u.UserID] = i
+		}
+	}
+	return split
+}
+
+// orderCounts is the skewed number of orders of a generated user: most users
+// have one or two, some have none and a few have many.
+var orderCounts = []int{0, 0, 1, 1, 1, 1, 2, 2, 3, 5}
+
+// newOrderMachines returns 0 to N orders for each of the given number of
+// users, split over n machines in OrderID order so that UserIDs are shuffled
+// across the machines.
+func newOrderMachines(rng *rand.Rand, users, n int) [][]Order {
+	var userIDs []int
+	for id := 1; id <= users; id++ {
+		for k := orderCounts[rng.Intn(len(orderCounts))]; k > 0; k-- {
+			userIDs = append(userIDs, id)
+		}
+	}
+	rng.Shuffle(len(userIDs), func(i, j int) { userIDs[i], userIDs[j] = userIDs[j], userIDs[i] })
+	orders := make([]Order, len(userIDs))
+	for i := range orders {
+		orders[i] = Order{OrderID: 101 + i, UserID: userIDs[i], Item: fmt.Sprintf("Item%d", 101+i), Price: 100 + rng.Intn(900)}
+	}
+	return splitEvenly(orders, n)
+}
@@ -0,0 +1,124 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Steps of the Order table scans of JOIN1, which JOIN2 and JOIN4 share.
+// StepRequestingMove and StepRespondingMove move a JOIN2 scan to the next
+// Order split.
+const (
+	StepScanningOrderTable Step = FirstScenarioStep + iota
+	StepRequestingMove
+	StepRespondingMove
+)
+
+// userPause is the number of ticks the result of a user is shown before the
+// next one starts.
+const userPause = TicksPerSecond * 3 / 10
+
+// JOIN1 joins a single User table with a single Order table by scanning the
+// whole Order table once per user, since a user may have any number of
+// orders.
+type JOIN1 struct {
+	Users  []User
+	Orders []Order
+
+	CurrentUserIndex int
+	OrderScanIndex   int
+	Matched          bool
+
+	dataset *Dataset
+}
+
+func (s *JOIN1) Name() string {
+	return "JOIN1"
+}
+
+func (s *JOIN1) Description() string {
+	return "Nested loop JOIN of a single User table and Order table"
+}
+
+func (s *JOIN1) UseDataset(d *Dataset) error {
+	if len(d.Users) == 0 {
+		return fmt.Errorf("JOIN1 needs two tables, %s has no parent table", d.Schema.OrderTable)
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *JOIN1) Setup(e *Engine, rng *rand.Rand) {
+	e.PacketTicks = 16
+	if s.dataset != nil {
+		s.Users = s.dataset.allUsers()
+		s.Orders = s.dataset.allOrders()
+		e.Schema = s.dataset.Schema
+		return
+	}
+
+	users := []User{
+		{UserID: 1, Name: "Alice"}, {UserID: 2, Name: "Bob"}, {UserID: 3, Name: "Charlie"}, {UserID: 4, Name: "David"}, {UserID: 5, Name: "Eve"},
+		{UserID: 6, Name: "Frank"}, {UserID: 7, Name: "Grace"}, {UserID: 8, Name: "Heidi"}, {UserID: 9, Name: "Ivan"}, {UserID: 10, Name: "Judy"},
+	}
+	s.Users = users
+	s.Orders = newOrderMachines(rng, len(users), 1)[0]
+}
+
+func (s *JOIN1) Reset(e *Engine) Step {
+	s.CurrentUserIndex = 0
+	s.resetPacket(e)
+	return StepRequesting
+}
+
+func (s *JOIN1) resetPacket(e *Engine) {
+	e.place(0, Endpoint{Table: TableUsers, Row: s.CurrentUserIndex})
+}
+
+func (s *JOIN1) Update(e *Engine) {
+	switch e.Step {
+	case StepRequesting:
+		s.OrderScanIndex = 0
+		s.Matched = false
+		e.send(0, Endpoint{Table: TableOrders, Row: 0})
+		e.Step = StepResponding
+	case StepResponding:
+		if e.movePacket(0) {
+			e.Step = StepScanningOrderTable
+		}
+	case StepScanningOrderTable:
+		if !e.scanDue() {
+			return
+		}
+		if s.OrderScanIndex >= len(s.Orders) {
+			e.Step = StepJoining
+			return
+		}
+		// Keep scanning after a match: the user may have more orders.
+		currentUser := s.Users[s.CurrentUserIndex]
+		if o := s.Orders[s.OrderScanIndex]; o.UserID == currentUser.UserID {
+			e.ShowJoined = true
+			e.joinMatch(currentUser, o, !s.Matched)
+			s.Matched = true
+		}
+		s.OrderScanIndex++
+		if s.OrderScanIndex < len(s.Orders) {
+			e.Packets[0].To.Row = s.OrderScanIndex
+		} else {
+			e.Step = StepJoining
+		}
+	case StepJoining:
+		e.ShowJoined = true
+		e.joinDone(s.Users[s.CurrentUserIndex], s.Matched)
+		e.hold(StepPaused, userPause, StepNextUser)
+	case StepNextUser:
+		if s.CurrentUserIndex+1 >= len(s.Users) {
+			// Keep the cursor on the last user while the result is shown.
+			e.pauseBeforeRestart()
+		} else {
+			s.CurrentUserIndex++
+			e.Step = StepRequesting
+			s.resetPacket(e)
+		}
+	}
+}
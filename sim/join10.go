@@ -0,0 +1,160 @@
+package sim
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+)
+
+// broadcastColor tells the copies of Items apart from the partial results.
+var broadcastColor = color.RGBA{R: 0x40, G: 0x80, B: 0xff, A: 0xff}
+
+// Steps of JOIN10.
+const (
+	StepBroadcastSend Step = FirstScenarioStep + iota
+	StepBroadcastArrive
+	StepLocalJoin
+	StepUnionSend
+	StepUnionArrive
+)
+
+// JOIN10 is a broadcast join of Orders with the small Items table. The Items
+// table is copied to every Order split, every split joins its own rows with
+// the copy in parallel and the root unions the partial results, so the large
+// Orders table never moves.
+//
+// The per-split state below is indexed by Order split.
+type JOIN10 struct {
+	Items         []ItemRow
+	OrderMachines [][]Order
+
+	// HasItems is set once the copy of Items has arrived at a split.
+	HasItems  []bool
+	ScanIndex []int
+	Partials  [][]OrderItem
+
+	// Result is the union of the partial results at the root.
+	Result []OrderItem
+
+	topology Topology
+}
+
+func (s *JOIN10) Name() string {
+	return "JOIN10"
+}
+
+func (s *JOIN10) Description() string {
+	return "Broadcast JOIN of a small Items table to every Order split"
+}
+
+func (s *JOIN10) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN10", t, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *JOIN10) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	e.PacketTicks = 60
+
+	// Orange is not in Items, so that LEFT OUTER and ANTI JOINs have orders
+	// without an item.
+	s.Items = []ItemRow{{Item: "Apple"}, {Item: "Banana"}, {Item: "Cherry"}, {Item: "Grape"}}
+	for i := range s.Items {
+		s.Items[i].Price = 100 + rng.Intn(900)
+	}
+	items := []string{"Apple", "Banana", "Cherry", "Grape", "Orange"}
+	t := s.topology.withDefaults(Topology{Orders: 4})
+	s.OrderMachines = splitEvenly(newItemOrders(rng, items, ordersPerSplit*t.Orders), t.Orders)
+
+	s.HasItems = make([]bool, len(s.OrderMachines))
+	s.ScanIndex = make([]int, len(s.OrderMachines))
+	s.Partials = make([][]OrderItem, len(s.OrderMachines))
+}
+
+func (s *JOIN10) Reset(e *Engine) Step {
+	for i := range s.OrderMachines {
+		s.HasItems[i] = false
+		s.ScanIndex[i] = 0
+		s.Partials[i] = nil
+	}
+	s.Result = nil
+	for i := range e.Packets {
+		e.Packets[i].Active = false
+	}
+	return StepBroadcastSend
+}
+
+// LookupItem returns the row of Items named item.
+func (s *JOIN10) LookupItem(item string) (ItemRow, bool) {
+	for _, row := range s.Items {
+		if row.Item == item {
+			return row, true
+		}
+	}
+	return ItemRow{}, false
+}
+
+// join returns the result row of o for the engine's JoinType, if there is
+// one.
+func (s *JOIN10) join(e *Engine, o Order) (OrderItem, bool) {
+	item, ok := s.LookupItem(o.Item)
+	switch e.JoinType {
+	case LeftOuterJoin:
+		return OrderItem{Order: o, Item: item, Null: !ok}, true
+	case SemiJoin:
+		return OrderItem{Order: o}, ok
+	case AntiJoin:
+		return OrderItem{Order: o}, !ok
+	}
+	return OrderItem{Order: o, Item: item}, ok
+}
+
+func (s *JOIN10) Update(e *Engine) {
+	switch e.Step {
+	case StepBroadcastSend:
+		// One copy of Items for every split, which can join once its copy
+		// has arrived.
+		for i := range s.OrderMachines {
+			p := e.spawn(Endpoint{Table: TableItems}, Endpoint{Table: TableOrders, Split: i},
+				fmt.Sprintf("%s x%d", TableItems, len(s.Items)), func() { s.HasItems[i] = true })
+			p.Color = broadcastColor
+		}
+		e.Step = StepBroadcastArrive
+	case StepBroadcastArrive:
+		if e.moveActive() {
+			e.Step = StepLocalJoin
+		}
+	case StepLocalJoin:
+		if !e.scanDue() {
+			return
+		}
+		// Every split joins its next row with its own copy of Items.
+		done := true
+		for i, machine := range s.OrderMachines {
+			if !s.HasItems[i] || s.ScanIndex[i] >= len(machine) {
+				continue
+			}
+			if row, ok := s.join(e, machine[s.ScanIndex[i]]); ok {
+				s.Partials[i] = append(s.Partials[i], row)
+			}
+			s.ScanIndex[i]++
+			done = false
+		}
+		if done {
+			e.Step = StepUnionSend
+		}
+	case StepUnionSend:
+		for i := range s.OrderMachines {
+			e.spawn(Endpoint{Table: TableOrders, Split: i}, Endpoint{Table: TableTopTier},
+				fmt.Sprintf("x%d", len(s.Partials[i])), func() { s.Result = append(s.Result, s.Partials[i]...) })
+		}
+		e.Step = StepUnionArrive
+	case StepUnionArrive:
+		if e.moveActive() {
+			e.pauseBeforeRestart()
+		}
+	}
+}
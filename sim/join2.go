@@ -0,0 +1,191 @@
+package sim
+
+import "math/rand"
+
+// JOIN2 joins Users and Orders that are each split over several machines.
+// The Orders are not ordered by UserID, so every user scans all the Order
+// splits one after another, collecting every match on the way.
+//
+// The per-user state below is indexed by User split.
+type JOIN2 struct {
+	UserMachines  [][]User
+	OrderMachines [][]Order
+
+	CurrentUserIndex      int
+	OrderScanIndex        []int
+	OrderScanMachineIndex []int
+	ScanDone              []bool
+	Matched               []bool
+	NeedsToMove           []bool
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (s *JOIN2) Name() string {
+	return "JOIN2"
+}
+
+func (s *JOIN2) Description() string {
+	return "JOIN of split Users and Orders not ordered by UserID"
+}
+
+func (s *JOIN2) UseDataset(d *Dataset) error {
+	if err := d.needUsers("JOIN2"); err != nil {
+		return err
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *JOIN2) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN2", t, TableUsers, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *JOIN2) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	e.PacketTicks = 36
+	if s.dataset != nil {
+		s.UserMachines = s.dataset.Users
+		s.OrderMachines = s.dataset.Orders
+		e.Schema = s.dataset.Schema
+	} else {
+		t := s.topology.withDefaults(Topology{Users: 2, Orders: 2})
+		s.UserMachines = newUserMachines(t.Users)
+		s.OrderMachines = newOrderMachines(rng, t.Users*usersPerSplit, t.Orders)
+	}
+
+	n := len(s.UserMachines)
+	s.OrderScanIndex = make([]int, n)
+	s.OrderScanMachineIndex = make([]int, n)
+	s.ScanDone = make([]bool, n)
+	s.Matched = make([]bool, n)
+	s.NeedsToMove = make([]bool, n)
+}
+
+func (s *JOIN2) Reset(e *Engine) Step {
+	s.CurrentUserIndex = 0
+	s.resetPackets(e)
+	return StepRequesting
+}
+
+func (s *JOIN2) resetPackets(e *Engine) {
+	for i := range s.UserMachines {
+		e.place(i, Endpoint{Table: TableUsers, Split: i, Row: s.CurrentUserIndex})
+	}
+}
+
+func (s *JOIN2) Update(e *Engine) {
+	switch e.Step {
+	case StepRequesting:
+		for i := range s.UserMachines {
+			s.OrderScanIndex[i] = 0
+			s.OrderScanMachineIndex[i] = 0 // All start at machine 0
+			s.ScanDone[i] = false
+			s.Matched[i] = false
+			s.NeedsToMove[i] = false
+			// Every user starts searching from OrderMachine 0
+			e.send(i, Endpoint{Table: TableOrders, Split: 0, Row: 0})
+		}
+		e.Step = StepResponding
+	case StepResponding:
+		packetsFinished := 0
+		for i := range s.UserMachines {
+			if e.movePacket(i) {
+				packetsFinished++
+			}
+		}
+		if packetsFinished == len(s.UserMachines) {
+			e.Step = StepScanningOrderTable
+		}
+	case StepScanningOrderTable:
+		if !e.scanDue() {
+			return
+		}
+		var needsToMove bool
+		allDone := true
+		for i := range s.UserMachines {
+			if s.ScanDone[i] || s.NeedsToMove[i] {
+				continue
+			}
+
+			currentUser := s.UserMachines[i][s.CurrentUserIndex]
+			scanningMachine := s.OrderScanMachineIndex[i]
+			machine := s.OrderMachines[scanningMachine]
+
+			if s.OrderScanIndex[i] < len(machine) {
+				// Keep scanning after a match: the user may have more orders.
+				if o := machine[s.OrderScanIndex[i]]; o.UserID == currentUser.UserID {
+					e.ShowJoined = true
+					e.joinMatch(currentUser, o, !s.Matched[i])
+					s.Matched[i] = true
+				}
+				s.OrderScanIndex[i]++
+				if s.OrderScanIndex[i] < len(machine) {
+					e.Packets[i].To = Endpoint{Table: TableOrders, Split: scanningMachine, Row: s.OrderScanIndex[i]}
+				}
+			}
+
+			if s.OrderScanIndex[i] >= len(machine) {
+				if scanningMachine < len(s.OrderMachines)-1 { // Move on to the next machine
+					s.NeedsToMove[i] = true
+					needsToMove = true
+				} else { // Finished scanning the last machine
+					s.ScanDone[i] = true
+				}
+			}
+			allDone = allDone && s.ScanDone[i]
+		}
+
+		if needsToMove {
+			e.Step = StepRequestingMove
+		} else if allDone {
+			e.Step = StepJoining
+		}
+	case StepRequestingMove:
+		for i := range s.UserMachines {
+			if s.NeedsToMove[i] {
+				// Restart from the user row towards the next OrderMachine
+				e.place(i, Endpoint{Table: TableUsers, Split: i, Row: s.CurrentUserIndex})
+				e.send(i, Endpoint{Table: TableOrders, Split: s.OrderScanMachineIndex[i] + 1, Row: 0})
+			}
+		}
+		e.Step = StepRespondingMove
+	case StepRespondingMove:
+		packetsFinished := 0
+		for i := range s.UserMachines {
+			if !s.NeedsToMove[i] {
+				packetsFinished++
+				continue
+			}
+			if e.movePacket(i) {
+				s.NeedsToMove[i] = false
+				s.OrderScanMachineIndex[i]++ // Now scanning the next machine
+				s.OrderScanIndex[i] = 0
+				packetsFinished++
+			}
+		}
+		if packetsFinished == len(s.UserMachines) {
+			e.Step = StepScanningOrderTable
+		}
+	case StepJoining:
+		e.ShowJoined = true
+		for i := range s.UserMachines {
+			e.joinDone(s.UserMachines[i][s.CurrentUserIndex], s.Matched[i])
+		}
+		e.hold(StepPaused, userPause, StepNextUser)
+	case StepNextUser:
+		if s.CurrentUserIndex+1 >= len(s.UserMachines[0]) {
+			// Keep the cursor on the last user while the result is shown.
+			e.pauseBeforeRestart()
+		} else {
+			s.CurrentUserIndex++
+			e.Step = StepRequesting
+			s.resetPackets(e)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sim
+
+import "math/rand"
+
+// Steps of the index lookups of JOIN3, which JOIN7, JOIN8 and JOIN9 share.
+const (
+	StepUserToIndexRequest Step = FirstScenarioStep + iota
+	StepUserToIndexResponse
+	StepIndexToOrderRequest
+	StepIndexToOrderResponse
+)
+
+// JOIN3 joins Users and Orders through a secondary index on Orders(UserID):
+// each user looks up its index entries first and then fetches one Order row
+// per entry. A user without an index entry fetches nothing.
+//
+// The per-user state below is indexed by User split.
+type JOIN3 struct {
+	indexTables
+
+	CurrentUserIndex int
+	IndexEntries     [][]Cursor
+	OrderRows        [][]Cursor
+}
+
+func (s *JOIN3) Name() string {
+	return "JOIN3"
+}
+
+func (s *JOIN3) Description() string {
+	return "JOIN through a secondary index on Orders(UserID)"
+}
+
+func (s *JOIN3) UseDataset(d *Dataset) error {
+	return s.useDataset("JOIN3", d)
+}
+
+func (s *JOIN3) SetTopology(t Topology) error {
+	return s.setTopology("JOIN3", t)
+}
+
+func (s *JOIN3) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	e.PacketTicks = 16
+	s.setup(e, rng)
+
+	s.IndexEntries = make([][]Cursor, len(s.UserMachines))
+	s.OrderRows = make([][]Cursor, len(s.UserMachines))
+}
+
+func (s *JOIN3) Reset(e *Engine) Step {
+	s.CurrentUserIndex = 0
+	s.resetPackets(e)
+	return StepUserToIndexRequest
+}
+
+func (s *JOIN3) resetPackets(e *Engine) {
+	for i := range s.UserMachines {
+		e.place(i, Endpoint{Table: TableUsers, Split: i, Row: s.CurrentUserIndex})
+	}
+}
+
+func (s *JOIN3) Update(e *Engine) {
+	switch e.Step {
+	case StepUserToIndexRequest:
+		for i := range s.UserMachines {
+			seek, entries := s.lookupIndex(s.UserMachines[i][s.CurrentUserIndex].UserID)
+			s.IndexEntries[i] = entries
+			s.OrderRows[i] = nil
+			e.send(i, Endpoint{Table: TableIndex, Split: seek.Split, Row: seek.Row})
+		}
+		e.Step = StepUserToIndexResponse
+
+	case StepUserToIndexResponse:
+		if e.moveActive() {
+			e.Step = StepIndexToOrderRequest
+		}
+
+	case StepIndexToOrderRequest:
+		// Every index entry fetches its Order row with its own packet.
+		p := 0
+		for i := range s.UserMachines {
+			for _, c := range s.IndexEntries[i] {
+				o := s.lookupOrder(s.IndexMachines[c.Split][c.Row].OrderID)
+				s.OrderRows[i] = append(s.OrderRows[i], o)
+				e.place(p, Endpoint{Table: TableIndex, Split: c.Split, Row: c.Row})
+				e.send(p, Endpoint{Table: TableOrders, Split: o.Split, Row: o.Row})
+				p++
+			}
+		}
+		e.Step = StepIndexToOrderResponse
+
+	case StepIndexToOrderResponse:
+		if !e.moveActive() {
+			return
+		}
+		for i := range s.UserMachines {
+			var orders []Order
+			for _, c := range s.OrderRows[i] {
+				orders = append(orders, s.OrderMachines[c.Split][c.Row])
+			}
+			e.joinUser(s.UserMachines[i][s.CurrentUserIndex], orders...)
+		}
+		e.ShowJoined = true
+		e.Step = StepJoining
+
+	case StepJoining:
+		if !e.scanDue() {
+			return
+		}
+		if s.CurrentUserIndex+1 >= len(s.UserMachines[0]) {
+			// Keep the cursor on the last user while the result is shown.
+			e.pauseBeforeRestart()
+		} else {
+			s.CurrentUserIndex++
+			e.Step = StepUserToIndexRequest
+			s.resetPackets(e)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sim
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// JOIN4 joins Orders that are interleaved in their parent Users
+// (INTERLEAVE IN PARENT). Every Order row is stored in the split of its User,
+// in UserID order, so each split joins its own rows and no packet is sent.
+//
+// OrderMachines[i] holds the orders of the users in UserMachines[i]. The
+// per-user state below is indexed by split.
+type JOIN4 struct {
+	UserMachines  [][]User
+	OrderMachines [][]Order
+
+	CurrentUserIndex int
+	OrderScanIndex   []int
+	ScanDone         []bool
+	Matched          []bool
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (s *JOIN4) Name() string {
+	return "JOIN4"
+}
+
+func (s *JOIN4) Description() string {
+	return "Local JOIN of Orders interleaved in their parent Users"
+}
+
+func (s *JOIN4) UseDataset(d *Dataset) error {
+	if err := d.needUsers("JOIN4"); err != nil {
+		return err
+	}
+	if err := d.needParents("JOIN4"); err != nil {
+		return err
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *JOIN4) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN4", t, TableUsers); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *JOIN4) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+
+	var orders []Order
+	if s.dataset != nil {
+		s.UserMachines = s.dataset.Users
+		orders = s.dataset.allOrders()
+		e.Schema = s.dataset.Schema
+	} else {
+		t := s.topology.withDefaults(Topology{Users: 2})
+		s.UserMachines = newUserMachines(t.Users)
+		orders = newOrderMachines(rng, t.Users*usersPerSplit, 1)[0]
+	}
+
+	// Store every order in the split of its user, keyed by (UserID, OrderID).
+	split := userSplits(s.UserMachines)
+	s.OrderMachines = make([][]Order, len(s.UserMachines))
+	for _, o := range orders {
+		s.OrderMachines[split[o.UserID]] = append(s.OrderMachines[split[o.UserID]], o)
+	}
+	for _, machine := range s.OrderMachines {
+		sort.Slice(machine, func(i, j int) bool {
+			if machine[i].UserID != machine[j].UserID {
+				return machine[i].UserID < machine[j].UserID
+			}
+			return machine[i].OrderID < machine[j].OrderID
+		})
+	}
+
+	s.OrderScanIndex = make([]int, len(s.UserMachines))
+	s.ScanDone = make([]bool, len(s.UserMachines))
+	s.Matched = make([]bool, len(s.UserMachines))
+}
+
+func (s *JOIN4) Reset(e *Engine) Step {
+	s.CurrentUserIndex = 0
+	for i := range s.OrderScanIndex {
+		s.OrderScanIndex[i] = 0
+		s.ScanDone[i] = false
+		s.Matched[i] = false
+	}
+	return StepScanningOrderTable
+}
+
+func (s *JOIN4) Update(e *Engine) {
+	switch e.Step {
+	case StepScanningOrderTable:
+		if !e.scanDue() {
+			return
+		}
+		// The children of a user directly follow the children of the previous
+		// one, so every split keeps scanning from where it stopped.
+		e.ShowJoined = true
+		allDone := true
+		for i, machine := range s.OrderMachines {
+			if s.ScanDone[i] {
+				continue
+			}
+			user := s.UserMachines[i][s.CurrentUserIndex]
+			if s.OrderScanIndex[i] < len(machine) && machine[s.OrderScanIndex[i]].UserID == user.UserID {
+				e.joinMatch(user, machine[s.OrderScanIndex[i]], !s.Matched[i])
+				s.Matched[i] = true
+				s.OrderScanIndex[i]++
+				allDone = false
+			} else {
+				e.joinDone(user, s.Matched[i])
+				s.ScanDone[i] = true
+			}
+		}
+		if allDone {
+			e.hold(StepPaused, userPause, StepNextUser)
+		}
+	case StepNextUser:
+		if s.CurrentUserIndex+1 >= len(s.UserMachines[0]) {
+			// Keep the cursor on the last user while the result is shown.
+			e.pauseBeforeRestart()
+		} else {
+			s.CurrentUserIndex++
+			for i := range s.ScanDone {
+				s.ScanDone[i] = false
+				s.Matched[i] = false
+			}
+			e.Step = StepScanningOrderTable
+		}
+	}
+}
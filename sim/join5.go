@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// HashBuckets is the number of buckets of the JOIN5 hash table.
+const HashBuckets = 4
+
+// Steps of JOIN5.
+const (
+	StepBuildSend Step = FirstScenarioStep + iota
+	StepBuildArrive
+	StepProbeSend
+	StepProbeArrive
+)
+
+// JOIN5 is a distributed hash join. In the build phase every User split
+// streams its rows to a coordinator, which inserts them into a hash table on
+// UserID. In the probe phase every Order split streams its rows to the
+// coordinator, which looks each one up in the bucket of its UserID. Users that
+// no order matched are returned once the probe phase is over.
+//
+// BuildIndex is indexed by User split, ProbeIndex and ProbeBucket by Order
+// split. ProbeBucket is the bucket the split's current row is probing, or -1.
+type JOIN5 struct {
+	UserMachines  [][]User
+	OrderMachines [][]Order
+
+	Buckets     [][]User
+	BuildIndex  []int
+	ProbeIndex  []int
+	ProbeBucket []int
+
+	// matched records the UserIDs some order has matched.
+	matched map[int]bool
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (s *JOIN5) Name() string {
+	return "JOIN5"
+}
+
+func (s *JOIN5) Description() string {
+	return "Hash JOIN with build and probe phases on a coordinator"
+}
+
+func (s *JOIN5) UseDataset(d *Dataset) error {
+	if len(d.Users) == 0 {
+		return fmt.Errorf("JOIN5 needs two tables, %s has no parent table", d.Schema.OrderTable)
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *JOIN5) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN5", t, TableUsers, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+// Bucket returns the hash table bucket of userID. Negative keys hash to the
+// same buckets as the positive ones.
+func (s *JOIN5) Bucket(userID int) int {
+	return (userID%HashBuckets + HashBuckets) % HashBuckets
+}
+
+func (s *JOIN5) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	e.PacketTicks = 30
+	if s.dataset != nil {
+		s.UserMachines = s.dataset.Users
+		s.OrderMachines = s.dataset.Orders
+		e.Schema = s.dataset.Schema
+	} else {
+		t := s.topology.withDefaults(Topology{Users: 2, Orders: 2})
+		s.UserMachines = newUserMachines(t.Users)
+		s.OrderMachines = newOrderMachines(rng, t.Users*usersPerSplit, t.Orders)
+	}
+	s.BuildIndex = make([]int, len(s.UserMachines))
+	s.ProbeIndex = make([]int, len(s.OrderMachines))
+	s.ProbeBucket = make([]int, len(s.OrderMachines))
+}
+
+func (s *JOIN5) Reset(e *Engine) Step {
+	s.Buckets = make([][]User, HashBuckets)
+	s.matched = map[int]bool{}
+	for i := range s.BuildIndex {
+		s.BuildIndex[i] = 0
+	}
+	for i := range s.ProbeIndex {
+		s.ProbeIndex[i] = 0
+		s.ProbeBucket[i] = -1
+	}
+	for i := range e.Packets {
+		e.Packets[i].Active = false
+	}
+	return StepBuildSend
+}
+
+func (s *JOIN5) Update(e *Engine) {
+	switch e.Step {
+	case StepBuildSend:
+		// Every User split sends its next row to the coordinator.
+		sent := false
+		pending := make([]int, HashBuckets)
+		for i, machine := range s.UserMachines {
+			if s.BuildIndex[i] >= len(machine) {
+				continue
+			}
+			b := s.Bucket(machine[s.BuildIndex[i]].UserID)
+			e.place(i, Endpoint{Table: TableUsers, Split: i, Row: s.BuildIndex[i]})
+			e.send(i, Endpoint{Table: TableCoordinator, Split: b, Row: len(s.Buckets[b]) + pending[b]})
+			pending[b]++
+			sent = true
+		}
+		if sent {
+			e.Step = StepBuildArrive
+		} else {
+			e.Step = StepProbeSend
+		}
+	case StepBuildArrive:
+		if !e.moveActive() {
+			return
+		}
+		for i, machine := range s.UserMachines {
+			if s.BuildIndex[i] >= len(machine) {
+				continue
+			}
+			u := machine[s.BuildIndex[i]]
+			s.Buckets[s.Bucket(u.UserID)] = append(s.Buckets[s.Bucket(u.UserID)], u)
+			s.BuildIndex[i]++
+		}
+		e.Step = StepBuildSend
+	case StepProbeSend:
+		// Every Order split sends its next row to the bucket of its UserID.
+		sent := false
+		for i, machine := range s.OrderMachines {
+			s.ProbeBucket[i] = -1
+			if s.ProbeIndex[i] >= len(machine) {
+				continue
+			}
+			o := machine[s.ProbeIndex[i]]
+			s.ProbeBucket[i] = s.Bucket(o.UserID)
+			e.place(i, Endpoint{Table: TableOrders, Split: i, Row: s.ProbeIndex[i]})
+			e.send(i, Endpoint{Table: TableCoordinator, Split: s.ProbeBucket[i], Row: -1})
+			sent = true
+		}
+		if sent {
+			e.Step = StepProbeArrive
+			return
+		}
+		for _, bucket := range s.Buckets {
+			for _, u := range bucket {
+				e.joinDone(u, s.matched[u.UserID])
+			}
+		}
+		e.ShowJoined = true
+		e.pauseBeforeRestart()
+	case StepProbeArrive:
+		if !e.moveActive() {
+			return
+		}
+		e.ShowJoined = true
+		for i, machine := range s.OrderMachines {
+			if s.ProbeIndex[i] >= len(machine) {
+				continue
+			}
+			o := machine[s.ProbeIndex[i]]
+			for _, u := range s.Buckets[s.ProbeBucket[i]] {
+				if u.UserID == o.UserID {
+					e.joinMatch(u, o, !s.matched[u.UserID])
+					s.matched[u.UserID] = true
+				}
+			}
+			s.ProbeIndex[i]++
+		}
+		e.Step = StepProbeSend
+	}
+}
@@ -0,0 +1,150 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// advance moves c to the next row of splits, skipping empty splits, and
+// reports whether there is one.
+func advance[T any](c *Cursor, splits [][]T) bool {
+	c.Row++
+	for c.Split < len(splits) && c.Row >= len(splits[c.Split]) {
+		c.Split++
+		c.Row = 0
+	}
+	return c.Split < len(splits)
+}
+
+// StepMerging is the step in which JOIN6 moves one of its cursors.
+const StepMerging = FirstScenarioStep
+
+// JOIN6 is a merge join. Users are split by UserID and Orders are re-keyed by
+// (UserID, OrderID), so both inputs are in UserID order. One cursor walks each
+// input and only the cursor with the smaller UserID moves, so every row is
+// read exactly once. A user is complete once the Order cursor has passed its
+// UserID.
+type JOIN6 struct {
+	UserMachines  [][]User
+	OrderMachines [][]Order
+
+	UserCursor  Cursor
+	OrderCursor Cursor
+	Comparisons int
+
+	// userMatched records whether an order has matched the current user.
+	userMatched bool
+
+	dataset  *Dataset
+	topology Topology
+}
+
+func (s *JOIN6) Name() string {
+	return "JOIN6"
+}
+
+func (s *JOIN6) Description() string {
+	return "Merge JOIN of Users and Orders both sorted by UserID"
+}
+
+func (s *JOIN6) UseDataset(d *Dataset) error {
+	if len(d.Users) == 0 {
+		return fmt.Errorf("JOIN6 needs two tables, %s has no parent table", d.Schema.OrderTable)
+	}
+	s.dataset = d
+	return nil
+}
+
+func (s *JOIN6) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN6", t, TableUsers, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *JOIN6) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+
+	var orders []Order
+	splits := 0
+	if s.dataset != nil {
+		s.UserMachines = s.dataset.Users
+		orders = s.dataset.allOrders()
+		splits = len(s.dataset.Orders)
+		e.Schema = s.dataset.Schema
+	} else {
+		t := s.topology.withDefaults(Topology{Users: 2, Orders: 2})
+		s.UserMachines = newUserMachines(t.Users)
+		orders = newOrderMachines(rng, t.Users*usersPerSplit, 1)[0]
+		splits = t.Orders
+	}
+
+	// Re-key Orders by (UserID, OrderID).
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].UserID != orders[j].UserID {
+			return orders[i].UserID < orders[j].UserID
+		}
+		return orders[i].OrderID < orders[j].OrderID
+	})
+	s.OrderMachines = splitEvenly(orders, splits)
+}
+
+func (s *JOIN6) Reset(e *Engine) Step {
+	s.UserCursor = Cursor{Row: -1}
+	s.OrderCursor = Cursor{Row: -1}
+	s.Comparisons = 0
+	s.userMatched = false
+	if !advance(&s.UserCursor, s.UserMachines) {
+		e.pauseBeforeRestart()
+		return e.Step
+	}
+	if !advance(&s.OrderCursor, s.OrderMachines) {
+		e.ShowJoined = true
+		s.finishUsers(e)
+		e.pauseBeforeRestart()
+		return e.Step
+	}
+	return StepMerging
+}
+
+// finishUsers completes the current user and every user after it once Orders
+// are exhausted.
+func (s *JOIN6) finishUsers(e *Engine) {
+	for more := true; more; more = advance(&s.UserCursor, s.UserMachines) {
+		e.joinDone(s.UserMachines[s.UserCursor.Split][s.UserCursor.Row], s.userMatched)
+		s.userMatched = false
+	}
+}
+
+func (s *JOIN6) Update(e *Engine) {
+	if e.Step != StepMerging || !e.scanDue() {
+		return
+	}
+	e.ShowJoined = true
+	u := s.UserMachines[s.UserCursor.Split][s.UserCursor.Row]
+	o := s.OrderMachines[s.OrderCursor.Split][s.OrderCursor.Row]
+	s.Comparisons++
+
+	more := true
+	switch {
+	case u.UserID == o.UserID:
+		// The next order may belong to the same user, so only Orders move.
+		e.joinMatch(u, o, !s.userMatched)
+		s.userMatched = true
+		more = advance(&s.OrderCursor, s.OrderMachines)
+	case u.UserID < o.UserID:
+		e.joinDone(u, s.userMatched)
+		s.userMatched = false
+		more = advance(&s.UserCursor, s.UserMachines)
+	default:
+		more = advance(&s.OrderCursor, s.OrderMachines)
+	}
+	if !more {
+		if s.OrderCursor.Split >= len(s.OrderMachines) {
+			s.finishUsers(e)
+		}
+		e.pauseBeforeRestart()
+	}
+}
@@ -0,0 +1,159 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// crossApplyBatchSize is the number of rows a User split looks up per batch.
+const crossApplyBatchSize = 5
+
+// Lookup is a user of a JOIN7 batch, where its index seek stopped and the
+// index entries and orders found for it.
+type Lookup struct {
+	User    Cursor
+	Index   Cursor
+	Entries []Cursor
+	Orders  []Cursor
+}
+
+// route is the way of one key of a batch from one split to another.
+type route struct {
+	from, to Endpoint
+}
+
+// JOIN7 is JOIN3 with Distributed Cross Apply. Every User split collects a
+// batch of UserIDs, groups them by the Index split that holds them and sends
+// one packet per Index split carrying all of its keys. The Index splits then
+// do the same with the OrderIDs they found, so the number of RPCs depends on
+// the number of splits instead of the number of rows.
+type JOIN7 struct {
+	indexTables
+
+	// BatchStart is the first row of the current batch in every User split.
+	BatchStart int
+	Batch      []Lookup
+
+	// RowRPCs counts the RPCs the one row at a time JOIN3 would have sent so
+	// far: one per user and one per index entry found.
+	RowRPCs int
+}
+
+func (s *JOIN7) Name() string {
+	return "JOIN7"
+}
+
+func (s *JOIN7) Description() string {
+	return "JOIN3 with batched lookups (Distributed Cross Apply)"
+}
+
+func (s *JOIN7) UseDataset(d *Dataset) error {
+	return s.useDataset("JOIN7", d)
+}
+
+func (s *JOIN7) SetTopology(t Topology) error {
+	return s.setTopology("JOIN7", t)
+}
+
+func (s *JOIN7) Setup(e *Engine, rng *rand.Rand) {
+	e.AutoStart = true
+	e.PacketTicks = 30
+	s.setup(e, rng)
+}
+
+func (s *JOIN7) Reset(e *Engine) Step {
+	s.BatchStart = 0
+	s.Batch = nil
+	s.RowRPCs = 0
+	return StepUserToIndexRequest
+}
+
+func (s *JOIN7) Update(e *Engine) {
+	switch e.Step {
+	case StepUserToIndexRequest:
+		s.Batch = nil
+		for i, machine := range s.UserMachines {
+			for r := s.BatchStart; r < s.BatchStart+crossApplyBatchSize && r < len(machine); r++ {
+				seek, entries := s.lookupIndex(machine[r].UserID)
+				s.Batch = append(s.Batch, Lookup{User: Cursor{Split: i, Row: r}, Index: seek, Entries: entries})
+			}
+		}
+		if len(s.Batch) == 0 {
+			e.pauseBeforeRestart()
+			return
+		}
+		var routes []route
+		for _, l := range s.Batch {
+			routes = append(routes, route{
+				from: Endpoint{Table: TableUsers, Split: l.User.Split, Row: l.User.Row},
+				to:   Endpoint{Table: TableIndex, Split: l.Index.Split, Row: l.Index.Row},
+			})
+		}
+		s.sendBatches(e, routes)
+		e.Step = StepUserToIndexResponse
+
+	case StepUserToIndexResponse:
+		if e.moveActive() {
+			e.Step = StepIndexToOrderRequest
+		}
+
+	case StepIndexToOrderRequest:
+		// Every index entry found fetches its order.
+		var routes []route
+		for i, l := range s.Batch {
+			s.Batch[i].Orders = nil
+			for _, c := range l.Entries {
+				o := s.lookupOrder(s.IndexMachines[c.Split][c.Row].OrderID)
+				s.Batch[i].Orders = append(s.Batch[i].Orders, o)
+				routes = append(routes, route{
+					from: Endpoint{Table: TableIndex, Split: c.Split, Row: c.Row},
+					to:   Endpoint{Table: TableOrders, Split: o.Split, Row: o.Row},
+				})
+			}
+		}
+		s.sendBatches(e, routes)
+		e.Step = StepIndexToOrderResponse
+
+	case StepIndexToOrderResponse:
+		if !e.moveActive() {
+			return
+		}
+		for _, l := range s.Batch {
+			var orders []Order
+			for _, c := range l.Orders {
+				orders = append(orders, s.OrderMachines[c.Split][c.Row])
+			}
+			e.joinUser(s.UserMachines[l.User.Split][l.User.Row], orders...)
+		}
+		e.ShowJoined = true
+		e.Step = StepJoining
+
+	case StepJoining:
+		if !e.scanDue() {
+			return
+		}
+		s.BatchStart += crossApplyBatchSize
+		e.Step = StepUserToIndexRequest
+	}
+}
+
+// sendBatches sends one packet for every pair of source and target splits in
+// routes, carrying all of its keys, and records the RPCs the one row at a time
+// JOIN3 would have sent instead. A packet starts at the first row of its group
+// and points at the first row it looks up.
+func (s *JOIN7) sendBatches(e *Engine, routes []route) {
+	type pair struct{ from, to int }
+	var groups []route
+	keys := map[pair]int{}
+	for _, r := range routes {
+		key := pair{r.from.Split, r.to.Split}
+		if _, ok := keys[key]; !ok {
+			groups = append(groups, r)
+		}
+		keys[key]++
+	}
+	for _, r := range groups {
+		e.spawn(r.from, r.to, fmt.Sprintf("x%d", keys[pair{r.from.Split, r.to.Split}]), nil)
+	}
+	s.RowRPCs += len(routes)
+}
@@ -0,0 +1,59 @@
+package sim
+
+import "math/rand"
+
+// JOIN8 is JOIN3 with a covering index: the index on Orders(UserID) stores
+// Item and Price, so every column the query reads is in the index entry and
+// the lookup ends at the Index split without the back-join to Orders.
+type JOIN8 struct {
+	JOIN3
+
+	// BackJoinsSkipped counts the Index to Order RPCs JOIN3 would have sent
+	// so far, one per index entry.
+	BackJoinsSkipped int
+}
+
+func (s *JOIN8) Name() string {
+	return "JOIN8"
+}
+
+func (s *JOIN8) Description() string {
+	return "JOIN3 with a covering index (STORING) and no back-join"
+}
+
+func (s *JOIN8) UseDataset(d *Dataset) error {
+	return s.useDataset("JOIN8", d)
+}
+
+func (s *JOIN8) SetTopology(t Topology) error {
+	return s.setTopology("JOIN8", t)
+}
+
+func (s *JOIN8) Setup(e *Engine, rng *rand.Rand) {
+	s.storing = true
+	s.JOIN3.Setup(e, rng)
+}
+
+func (s *JOIN8) Reset(e *Engine) Step {
+	s.BackJoinsSkipped = 0
+	return s.JOIN3.Reset(e)
+}
+
+func (s *JOIN8) Update(e *Engine) {
+	if e.Step != StepIndexToOrderRequest {
+		s.JOIN3.Update(e)
+		return
+	}
+	// The index entries hold every column, so join them where they are.
+	for i := range s.UserMachines {
+		var orders []Order
+		for _, c := range s.IndexEntries[i] {
+			entry := s.IndexMachines[c.Split][c.Row]
+			orders = append(orders, Order{OrderID: entry.OrderID, UserID: entry.UserID, Item: entry.Item, Price: entry.Price})
+		}
+		s.BackJoinsSkipped += len(orders)
+		e.joinUser(s.UserMachines[i][s.CurrentUserIndex], orders...)
+	}
+	e.ShowJoined = true
+	e.Step = StepJoining
+}
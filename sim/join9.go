@@ -0,0 +1,66 @@
+package sim
+
+import "math/rand"
+
+// JOIN9 is JOIN3 with the index on Orders(UserID) interleaved in Users
+// (INTERLEAVE IN Users). Every index entry is stored in the split of its
+// user, so the User to Index lookup is a local read and only the back-join to
+// Orders crosses machines.
+type JOIN9 struct {
+	JOIN3
+
+	// LocalLookups counts the User to Index RPCs JOIN3 would have sent so far.
+	LocalLookups int
+}
+
+func (s *JOIN9) Name() string {
+	return "JOIN9"
+}
+
+func (s *JOIN9) Description() string {
+	return "JOIN3 with the index interleaved in Users"
+}
+
+func (s *JOIN9) UseDataset(d *Dataset) error {
+	if err := s.useDataset("JOIN9", d); err != nil {
+		return err
+	}
+	return d.needParents("JOIN9")
+}
+
+// The index follows the User splits, so only Users and Orders can be split.
+func (s *JOIN9) SetTopology(t Topology) error {
+	if err := checkTopology("JOIN9", t, TableUsers, TableOrders); err != nil {
+		return err
+	}
+	s.topology = t
+	return nil
+}
+
+func (s *JOIN9) Setup(e *Engine, rng *rand.Rand) {
+	s.interleaved = true
+	s.JOIN3.Setup(e, rng)
+}
+
+func (s *JOIN9) Reset(e *Engine) Step {
+	s.LocalLookups = 0
+	return s.JOIN3.Reset(e)
+}
+
+func (s *JOIN9) Update(e *Engine) {
+	if e.Step != StepUserToIndexRequest {
+		s.JOIN3.Update(e)
+		return
+	}
+	// The index entries of a user are on its own machine: read them without
+	// an RPC.
+	if !e.scanDue() {
+		return
+	}
+	for i := range s.UserMachines {
+		_, s.IndexEntries[i] = s.lookupIndex(s.UserMachines[i][s.CurrentUserIndex].UserID)
+		s.OrderRows[i] = nil
+	}
+	s.LocalLookups += len(s.UserMachines)
+	e.Step = StepIndexToOrderRequest
+}
@@ -0,0 +1,218 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// newJoinDataset generates two splits of users and their orders.
+func newJoinDataset(rng *rand.Rand) *Dataset {
+	return &Dataset{
+		Schema: DefaultSchema,
+		Users:  newUserMachines(2),
+		Orders: newOrderMachines(rng, 2*usersPerSplit, 2),
+	}
+}
+
+// shiftUserIDs returns d with every UserID moved by delta, keeping the order
+// of the keys.
+func shiftUserIDs(d *Dataset, delta int) *Dataset {
+	shifted := &Dataset{Schema: d.Schema}
+	for _, split := range d.Users {
+		users := slices.Clone(split)
+		for i := range users {
+			users[i].UserID += delta
+		}
+		shifted.Users = append(shifted.Users, users)
+	}
+	for _, split := range d.Orders {
+		orders := slices.Clone(split)
+		for i := range orders {
+			orders[i].UserID += delta
+		}
+		shifted.Orders = append(shifted.Orders, orders)
+	}
+	return shifted
+}
+
+// nestedLoopJoin is the reference the scenarios are checked against.
+func nestedLoopJoin(users []User, orders []Order, t JoinType) []JoinedData {
+	var joined []JoinedData
+	for _, u := range users {
+		matched := false
+		for _, o := range orders {
+			if o.UserID != u.UserID {
+				continue
+			}
+			switch t {
+			case InnerJoin, LeftOuterJoin:
+				joined = append(joined, JoinedData{User: u, Order: o})
+			case SemiJoin:
+				if !matched {
+					joined = append(joined, JoinedData{User: u})
+				}
+			}
+			matched = true
+		}
+		if !matched {
+			switch t {
+			case LeftOuterJoin:
+				joined = append(joined, JoinedData{User: u, Null: true})
+			case AntiJoin:
+				joined = append(joined, JoinedData{User: u})
+			}
+		}
+	}
+	return joined
+}
+
+// joinedRows formats joined rows in a stable order.
+func joinedRows(joined []JoinedData) []string {
+	rows := make([]string, len(joined))
+	for i, j := range joined {
+		rows[i] = fmt.Sprintf("%d:%d:%v", j.User.UserID, j.Order.OrderID, j.Null)
+	}
+	slices.Sort(rows)
+	return rows
+}
+
+func TestJoinTypes(t *testing.T) {
+	scenarios := []func() DatasetScenario{
+		func() DatasetScenario { return &JOIN1{} },
+		func() DatasetScenario { return &JOIN2{} },
+		func() DatasetScenario { return &JOIN3{} },
+		func() DatasetScenario { return &JOIN4{} },
+		func() DatasetScenario { return &JOIN5{} },
+		func() DatasetScenario { return &JOIN6{} },
+		func() DatasetScenario { return &JOIN7{} },
+		func() DatasetScenario { return &JOIN8{} },
+		func() DatasetScenario { return &JOIN9{} },
+	}
+	datasets := map[string]*Dataset{
+		// Negative keys start at -5.
+		"negative": shiftUserIDs(newJoinDataset(rand.New(rand.NewSource(1))), -6),
+	}
+	for seed := int64(1); seed <= 5; seed++ {
+		datasets[fmt.Sprintf("seed%d", seed)] = newJoinDataset(rand.New(rand.NewSource(seed)))
+	}
+	for name, d := range datasets {
+		for _, jt := range []JoinType{InnerJoin, LeftOuterJoin, SemiJoin, AntiJoin} {
+			want := joinedRows(nestedLoopJoin(d.allUsers(), d.allOrders(), jt))
+			for _, newScenario := range scenarios {
+				s := newScenario()
+				t.Run(fmt.Sprintf("%s/%v/%s", s.Name(), jt, name), func(t *testing.T) {
+					if err := s.UseDataset(d); err != nil {
+						t.Fatal(err)
+					}
+					e := NewEngine(s, rand.New(rand.NewSource(1)))
+					e.JoinType = jt
+					e.Start()
+					for i := 0; !finished(e); i++ {
+						if i == 100000 {
+							t.Fatal("did not finish")
+						}
+						e.Tick()
+					}
+					if got := joinedRows(e.Joined); !slices.Equal(got, want) {
+						t.Errorf("joined %v, want %v", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestJOIN7Batching(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		e7, _ := run(t, &JOIN7{}, seed, 1)
+		e3, _ := run(t, &JOIN3{}, seed, 1)
+		s := e7.Scenario.(*JOIN7)
+		if s.RowRPCs != len(e3.RPCs) {
+			t.Errorf("seed %d: RowRPCs = %d, want the %d RPCs of JOIN3", seed, s.RowRPCs, len(e3.RPCs))
+		}
+		if len(e7.RPCs) >= s.RowRPCs {
+			t.Errorf("seed %d: JOIN7 sent %d RPCs, want fewer than the %d of one row at a time", seed, len(e7.RPCs), s.RowRPCs)
+		}
+	}
+}
+
+// nestedLoopItems joins orders with items the way JOIN10 is checked against.
+func nestedLoopItems(orders []Order, items []ItemRow, t JoinType) []string {
+	var rows []string
+	for _, o := range orders {
+		matched := false
+		for _, item := range items {
+			if item.Item != o.Item {
+				continue
+			}
+			if t == InnerJoin || t == LeftOuterJoin {
+				rows = append(rows, fmt.Sprintf("%d:%s:%d", o.OrderID, item.Item, item.Price))
+			}
+			matched = true
+		}
+		switch {
+		case matched && t == SemiJoin, !matched && t == AntiJoin:
+			rows = append(rows, fmt.Sprint(o.OrderID))
+		case !matched && t == LeftOuterJoin:
+			rows = append(rows, fmt.Sprintf("%d:NULL", o.OrderID))
+		}
+	}
+	slices.Sort(rows)
+	return rows
+}
+
+func TestJOIN10JoinTypes(t *testing.T) {
+	for _, jt := range []JoinType{InnerJoin, LeftOuterJoin, SemiJoin, AntiJoin} {
+		for _, splits := range []int{1, 4, 6} {
+			for seed := int64(1); seed <= 3; seed++ {
+				t.Run(fmt.Sprintf("%v/%d splits/seed%d", jt, splits, seed), func(t *testing.T) {
+					s := &JOIN10{}
+					if err := s.SetTopology(Topology{Orders: splits}); err != nil {
+						t.Fatal(err)
+					}
+					e := NewEngine(s, rand.New(rand.NewSource(seed)))
+					e.JoinType = jt
+					e.Start()
+					for i := 0; !finished(e); i++ {
+						if i == 100000 {
+							t.Fatal("did not finish")
+						}
+						e.Tick()
+					}
+					var got []string
+					for _, row := range s.Result {
+						switch {
+						case jt == SemiJoin || jt == AntiJoin:
+							got = append(got, fmt.Sprint(row.Order.OrderID))
+						case row.Null:
+							got = append(got, fmt.Sprintf("%d:NULL", row.Order.OrderID))
+						default:
+							got = append(got, fmt.Sprintf("%d:%s:%d", row.Order.OrderID, row.Item.Item, row.Item.Price))
+						}
+					}
+					slices.Sort(got)
+					if want := nestedLoopItems(slices.Concat(s.OrderMachines...), s.Items, jt); !slices.Equal(got, want) {
+						t.Errorf("got %v, want %v", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestJoinEnd(t *testing.T) {
+	scenarios := []Scenario{&JOIN1{}, &JOIN2{}, &JOIN3{}, &JOIN4{}, &JOIN5{}, &JOIN6{}, &JOIN7{}, &JOIN8{}, &JOIN9{}, &JOIN10{}}
+	for _, s := range scenarios {
+		e, _ := run(t, s, 1, 1)
+		rpcs, joined := len(e.RPCs), len(e.Joined)
+		// The result stays until the pause is over.
+		for range restartPause - 1 {
+			e.Tick()
+		}
+		if e.Step != StepPauseBeforeRestart || len(e.RPCs) != rpcs || len(e.Joined) != joined {
+			t.Errorf("%s: restarted before the result was shown for %d ticks", s.Name(), restartPause)
+		}
+	}
+}
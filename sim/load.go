@@ -0,0 +1,425 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the layout of a scenario file. JSON files use the same keys.
+//
+// A file defines one or two tables. With two tables, the child table has a
+// foreign key to the parent: the parent is shown as the User table and the
+// child as the Order table. A single table is shown as the Order table.
+//
+// Columns are mapped onto the rows the animations use as follows:
+//
+//	parent: primary key -> UserID, first STRING column -> Name
+//	child:  primary key -> OrderID, foreign key -> UserID,
+//	        first STRING column -> Item, first other INT64 column -> Price
+type File struct {
+	Scenario string      `yaml:"scenario"`
+	Tables   []FileTable `yaml:"tables"`
+}
+
+type FileTable struct {
+	Name       string       `yaml:"name"`
+	Columns    []FileColumn `yaml:"columns"`
+	PrimaryKey string       `yaml:"primaryKey"`
+	ForeignKey *ForeignKey  `yaml:"foreignKey"`
+
+	// SplitPoints are the primary keys at which a new split starts.
+	SplitPoints []int64 `yaml:"splitPoints"`
+
+	// Either Rows or Generate provides the data.
+	Rows     [][]any   `yaml:"rows"`
+	Generate *Generate `yaml:"generate"`
+}
+
+type FileColumn struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// Values, Min and Max control generated data.
+	// STRING columns pick from Values, INT64 columns from Min..Max.
+	Values []string `yaml:"values"`
+	Min    *int64   `yaml:"min"`
+	Max    *int64   `yaml:"max"`
+}
+
+type ForeignKey struct {
+	Column     string `yaml:"column"`
+	References string `yaml:"references"`
+}
+
+// Generate asks for Rows generated rows with primary keys counting up from
+// Start (default 1).
+type Generate struct {
+	Rows  int   `yaml:"rows"`
+	Start int64 `yaml:"start"`
+}
+
+// Column types supported in scenario files.
+const (
+	TypeInt64  = "INT64"
+	TypeString = "STRING"
+)
+
+// LoadDataset reads a YAML or JSON scenario file. Generated rows are drawn
+// from rng.
+func LoadDataset(path string, rng *rand.Rand) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, err := ReadDataset(f, rng)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// ReadDataset decodes and validates a scenario file.
+func ReadDataset(r io.Reader, rng *rand.Rand) (*Dataset, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var file File
+	if err := dec.Decode(&file); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty scenario file")
+		}
+		return nil, err
+	}
+	return file.Dataset(rng)
+}
+
+// Dataset validates the file and converts it into a Dataset.
+func (f *File) Dataset(rng *rand.Rand) (*Dataset, error) {
+	if len(f.Tables) == 0 || len(f.Tables) > 2 {
+		return nil, fmt.Errorf("want 1 or 2 tables, got %d", len(f.Tables))
+	}
+	for i := range f.Tables {
+		if err := f.Tables[i].validate(); err != nil {
+			return nil, fmt.Errorf("table %q: %w", f.Tables[i].Name, err)
+		}
+	}
+	if len(f.Tables) == 2 && f.Tables[0].Name == f.Tables[1].Name {
+		return nil, fmt.Errorf("table %q: defined twice", f.Tables[0].Name)
+	}
+
+	d := &Dataset{Scenario: f.Scenario, Schema: DefaultSchema}
+	parent, child, err := f.roles()
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []int64
+	if parent != nil {
+		rows, err := parent.rows(rng, nil)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", parent.Name, err)
+		}
+		nameCol := parent.firstColumn(TypeString)
+		d.Schema.UserTable = parent.Name
+		d.Schema.UserID = parent.PrimaryKey
+		d.Schema.UserName = parent.Columns[nameCol].Name
+		d.Users = make([][]User, len(parent.SplitPoints)+1)
+		pk := parent.column(parent.PrimaryKey)
+		for _, row := range rows {
+			id := row[pk].(int64)
+			userIDs = append(userIDs, id)
+			s := parent.splitOf(id)
+			d.Users[s] = append(d.Users[s], User{UserID: int(id), Name: row[nameCol].(string)})
+		}
+	}
+
+	rows, err := child.rows(rng, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("table %q: %w", child.Name, err)
+	}
+	pk := child.column(child.PrimaryKey)
+	fk := -1
+	if child.ForeignKey != nil {
+		fk = child.column(child.ForeignKey.Column)
+		d.Schema.OrderUserID = child.ForeignKey.Column
+	}
+	itemCol := child.firstColumn(TypeString)
+	priceCol := -1
+	for i, c := range child.Columns {
+		if c.Type == TypeInt64 && i != pk && i != fk {
+			priceCol = i
+			d.Schema.Price = c.Name
+			break
+		}
+	}
+	d.Schema.OrderTable = child.Name
+	d.Schema.OrderID = child.PrimaryKey
+	d.Schema.Item = child.Columns[itemCol].Name
+	d.Orders = make([][]Order, len(child.SplitPoints)+1)
+	for _, row := range rows {
+		id := row[pk].(int64)
+		o := Order{OrderID: int(id), Item: row[itemCol].(string)}
+		if fk >= 0 {
+			o.UserID = int(row[fk].(int64))
+		}
+		if priceCol >= 0 {
+			o.Price = int(row[priceCol].(int64))
+		}
+		s := child.splitOf(id)
+		d.Orders[s] = append(d.Orders[s], o)
+	}
+	return d, nil
+}
+
+// roles returns the parent and child table. The parent is nil for a file
+// with a single table.
+func (f *File) roles() (parent, child *FileTable, err error) {
+	if len(f.Tables) == 1 {
+		t := &f.Tables[0]
+		if t.ForeignKey != nil {
+			return nil, nil, fmt.Errorf("table %q: foreign key references %q, which is not defined", t.Name, t.ForeignKey.References)
+		}
+		return nil, t, nil
+	}
+	for i := range f.Tables {
+		t, other := &f.Tables[i], &f.Tables[1-i]
+		if t.ForeignKey == nil {
+			continue
+		}
+		if t.ForeignKey.References != other.Name {
+			return nil, nil, fmt.Errorf("table %q: foreign key references %q, which is not defined", t.Name, t.ForeignKey.References)
+		}
+		if other.ForeignKey != nil {
+			return nil, nil, fmt.Errorf("tables %q and %q reference each other", t.Name, other.Name)
+		}
+		return other, t, nil
+	}
+	return nil, nil, fmt.Errorf("tables %q and %q: one of them needs a foreign key to the other", f.Tables[0].Name, f.Tables[1].Name)
+}
+
+func (t *FileTable) validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(t.Columns) == 0 {
+		return errors.New("no columns")
+	}
+	seen := map[string]bool{}
+	for _, c := range t.Columns {
+		if c.Name == "" {
+			return errors.New("column without a name")
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("column %q: defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Type != TypeInt64 && c.Type != TypeString {
+			return fmt.Errorf("column %q: unsupported type %q, want %s or %s", c.Name, c.Type, TypeInt64, TypeString)
+		}
+		lo, hi := c.bounds()
+		if lo > hi {
+			return fmt.Errorf("column %q: min %d is greater than max %d", c.Name, lo, hi)
+		}
+		if uint64(hi)-uint64(lo) >= math.MaxInt64 {
+			return fmt.Errorf("column %q: range %d..%d is too wide", c.Name, lo, hi)
+		}
+	}
+	if t.PrimaryKey == "" {
+		return errors.New("primaryKey is required")
+	}
+	if pk := t.column(t.PrimaryKey); pk < 0 {
+		return fmt.Errorf("primary key %q is not a column", t.PrimaryKey)
+	} else if t.Columns[pk].Type != TypeInt64 {
+		return fmt.Errorf("primary key %q must be INT64", t.PrimaryKey)
+	}
+	if t.ForeignKey != nil {
+		fk := t.column(t.ForeignKey.Column)
+		if fk < 0 {
+			return fmt.Errorf("foreign key %q is not a column", t.ForeignKey.Column)
+		}
+		if t.Columns[fk].Type != TypeInt64 {
+			return fmt.Errorf("foreign key %q must be INT64", t.ForeignKey.Column)
+		}
+		if t.ForeignKey.Column == t.PrimaryKey {
+			return fmt.Errorf("foreign key %q cannot be the primary key", t.ForeignKey.Column)
+		}
+	}
+	if t.firstColumn(TypeString) < 0 {
+		return errors.New("needs a STRING column")
+	}
+	for i := 1; i < len(t.SplitPoints); i++ {
+		if t.SplitPoints[i] <= t.SplitPoints[i-1] {
+			return fmt.Errorf("splitPoints must be in ascending order, got %d after %d", t.SplitPoints[i], t.SplitPoints[i-1])
+		}
+	}
+	switch {
+	case len(t.Rows) > 0 && t.Generate != nil:
+		return errors.New("set either rows or generate, not both")
+	case len(t.Rows) == 0 && t.Generate == nil:
+		return errors.New("no rows; set rows or generate")
+	case t.Generate != nil && t.Generate.Rows <= 0:
+		return fmt.Errorf("generate.rows must be positive, got %d", t.Generate.Rows)
+	}
+	return nil
+}
+
+// rows returns the typed rows of the table sorted by primary key. Foreign
+// keys must be one of parentKeys.
+func (t *FileTable) rows(rng *rand.Rand, parentKeys []int64) ([][]any, error) {
+	var rows [][]any
+	if t.Generate != nil {
+		if t.ForeignKey != nil && len(parentKeys) == 0 {
+			return nil, fmt.Errorf("cannot generate foreign keys, %q has no rows", t.ForeignKey.References)
+		}
+		rows = t.generate(rng, parentKeys)
+	} else {
+		for i, raw := range t.Rows {
+			row, err := t.convert(raw)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	pk := t.column(t.PrimaryKey)
+	keys := map[int64]bool{}
+	for i, row := range rows {
+		id := row[pk].(int64)
+		if keys[id] {
+			return nil, fmt.Errorf("row %d: duplicate primary key %d", i+1, id)
+		}
+		keys[id] = true
+	}
+	if t.ForeignKey != nil {
+		fk := t.column(t.ForeignKey.Column)
+		parents := map[int64]bool{}
+		for _, id := range parentKeys {
+			parents[id] = true
+		}
+		for i, row := range rows {
+			if id := row[fk].(int64); !parents[id] {
+				return nil, fmt.Errorf("row %d: column %q: %d does not exist in %q", i+1, t.ForeignKey.Column, id, t.ForeignKey.References)
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][pk].(int64) < rows[j][pk].(int64) })
+	return rows, nil
+}
+
+// convert checks a raw row against the columns.
+func (t *FileTable) convert(raw []any) ([]any, error) {
+	if len(raw) != len(t.Columns) {
+		return nil, fmt.Errorf("want %d values, got %d", len(t.Columns), len(raw))
+	}
+	row := make([]any, len(raw))
+	for i, c := range t.Columns {
+		switch v := raw[i].(type) {
+		case int:
+			if c.Type == TypeInt64 {
+				row[i] = int64(v)
+				continue
+			}
+		case string:
+			if c.Type == TypeString {
+				row[i] = v
+				continue
+			}
+		}
+		return nil, fmt.Errorf("column %q: want %s, got %v", c.Name, c.Type, raw[i])
+	}
+	return row, nil
+}
+
+func (t *FileTable) generate(rng *rand.Rand, parentKeys []int64) [][]any {
+	start := t.Generate.Start
+	if start == 0 {
+		start = 1
+	}
+	rows := make([][]any, t.Generate.Rows)
+	for i := range rows {
+		id := start + int64(i)
+		row := make([]any, len(t.Columns))
+		for j, c := range t.Columns {
+			switch {
+			case c.Name == t.PrimaryKey:
+				row[j] = id
+			case t.ForeignKey != nil && c.Name == t.ForeignKey.Column:
+				row[j] = parentKeys[rng.Intn(len(parentKeys))]
+			case c.Type == TypeString && len(c.Values) > 0:
+				row[j] = c.Values[rng.Intn(len(c.Values))]
+			case c.Type == TypeString:
+				row[j] = fmt.Sprintf("%s%d", c.Name, id)
+			default:
+				lo, hi := c.bounds()
+				row[j] = lo + rng.Int63n(hi-lo+1)
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// The range generated INT64 values are drawn from unless Min and Max are set.
+const (
+	defaultMin = 100
+	defaultMax = 999
+)
+
+// bounds returns the range generated values of c are drawn from. A missing
+// bound is the default one, unless that would leave the range empty; then it
+// lies the width of the default range away from the bound that is set.
+func (c FileColumn) bounds() (lo, hi int64) {
+	const width = defaultMax - defaultMin
+	switch {
+	case c.Min != nil && c.Max != nil:
+		return *c.Min, *c.Max
+	case c.Min != nil && *c.Min > defaultMax:
+		if *c.Min > math.MaxInt64-width {
+			return *c.Min, math.MaxInt64
+		}
+		return *c.Min, *c.Min + width
+	case c.Min != nil:
+		return *c.Min, defaultMax
+	case c.Max != nil && *c.Max < defaultMin:
+		if *c.Max < math.MinInt64+width {
+			return math.MinInt64, *c.Max
+		}
+		return *c.Max - width, *c.Max
+	case c.Max != nil:
+		return defaultMin, *c.Max
+	}
+	return defaultMin, defaultMax
+}
+
+// column returns the index of the named column, or -1.
+func (t *FileTable) column(name string) int {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// firstColumn returns the index of the first column of the given type, or -1.
+func (t *FileTable) firstColumn(typ string) int {
+	for i, c := range t.Columns {
+		if c.Type == typ {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitOf returns the split that stores the given primary key.
+func (t *FileTable) splitOf(id int64) int {
+	return sort.Search(len(t.SplitPoints), func(i int) bool { return t.SplitPoints[i] > id })
+}
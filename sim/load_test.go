@@ -0,0 +1,196 @@
+package sim
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// users is a parent table the cases below add a child table to.
+const users = `
+tables:
+  - name: Users
+    columns:
+      - {name: UserId, type: INT64}
+      - {name: Name, type: STRING}
+    primaryKey: UserId
+    rows:
+      - [1, Ann]
+      - [2, Bob]
+`
+
+func TestReadDatasetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			name: "duplicate primary key",
+			file: users + `
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: UserId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    foreignKey: {column: UserId, references: Users}
+    rows:
+      - [10, 1, Apple]
+      - [10, 2, Pear]
+`,
+			want: `table "Orders": row 2: duplicate primary key 10`,
+		},
+		{
+			name: "missing parent",
+			file: users + `
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: UserId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    foreignKey: {column: UserId, references: Users}
+    rows:
+      - [10, 1, Apple]
+      - [11, 3, Pear]
+`,
+			want: `row 2: column "UserId": 3 does not exist in "Users"`,
+		},
+		{
+			name: "split points out of order",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    splitPoints: [20, 10]
+    generate: {rows: 5}
+`,
+			want: "splitPoints must be in ascending order, got 10 after 20",
+		},
+		{
+			name: "rows and generate",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    rows:
+      - [1, Apple]
+    generate: {rows: 5}
+`,
+			want: "set either rows or generate, not both",
+		},
+		{
+			name: "string for INT64",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    rows:
+      - [one, Apple]
+`,
+			want: `row 1: column "OrderId": want INT64, got one`,
+		},
+		{
+			name: "INT64 for STRING",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+    primaryKey: OrderId
+    rows:
+      - [1, 2]
+`,
+			want: `row 1: column "Item": want STRING, got 2`,
+		},
+		{
+			name: "min above max",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+      - {name: Price, type: INT64, min: 50, max: 10}
+    primaryKey: OrderId
+    generate: {rows: 5}
+`,
+			want: `column "Price": min 50 is greater than max 10`,
+		},
+		{
+			name: "range too wide",
+			file: `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+      - {name: Price, type: INT64, min: -9223372036854775808, max: 9223372036854775807}
+    primaryKey: OrderId
+    generate: {rows: 5}
+`,
+			want: `column "Price": range -9223372036854775808..9223372036854775807 is too wide`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadDataset(strings.NewReader(tt.file), rand.New(rand.NewSource(1)))
+			if err == nil {
+				t.Fatalf("ReadDataset succeeded, want error %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadDataset error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadDatasetBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds string
+		lo, hi int
+	}{
+		{"default", "", 100, 999},
+		{"min only", "min: 500", 500, 999},
+		{"min above default max", "min: 5000", 5000, 5899},
+		{"max only", "max: 500", 100, 500},
+		{"max below default min", "max: 50", -849, 50},
+		{"min and max", "min: 7, max: 7", 7, 7},
+		{"max near int64 max", "min: 9223372036854775000", 9223372036854775000, 9223372036854775807},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := `
+tables:
+  - name: Orders
+    columns:
+      - {name: OrderId, type: INT64}
+      - {name: Item, type: STRING}
+      - {name: Price, type: INT64, ` + tt.bounds + `}
+    primaryKey: OrderId
+    generate: {rows: 50}
+`
+			d, err := ReadDataset(strings.NewReader(file), rand.New(rand.NewSource(1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range d.Orders[0] {
+				if o.Price < tt.lo || o.Price > tt.hi {
+					t.Errorf("order %d: price %d not in %d..%d", o.OrderID, o.Price, tt.lo, tt.hi)
+				}
+			}
+		})
+	}
+}
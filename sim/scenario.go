@@ -0,0 +1,21 @@
+package sim
+
+import "math/rand"
+
+// Scenario is the logic of one animation.
+//
+// The Engine owns the parts every animation shares (steps, packets, the RPC
+// log and the scheduler) and calls into the Scenario for the rest.
+type Scenario interface {
+	Name() string
+	Description() string
+
+	// Setup generates the dataset and configures the engine.
+	Setup(e *Engine, rng *rand.Rand)
+
+	// Reset clears the state of the previous run and returns the first step.
+	Reset(e *Engine) Step
+
+	// Update runs one simulation tick of the current step.
+	Update(e *Engine)
+}
@@ -0,0 +1,67 @@
+package sim
+
+// Speed limits accepted by SetSpeed.
+const (
+	MinSpeed = 0.25
+	MaxSpeed = 8.0
+)
+
+// Scheduler converts frame ticks into simulation ticks.
+//
+// Every step transition, packet move and scan is counted in simulation ticks,
+// so pausing or changing the speed here affects the whole animation at once.
+type Scheduler struct {
+	speed  float64
+	paused bool
+	budget float64
+	held   int
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{speed: 1}
+}
+
+func (s *Scheduler) Pause() {
+	s.paused = true
+}
+
+func (s *Scheduler) Resume() {
+	s.paused = false
+}
+
+func (s *Scheduler) Paused() bool {
+	return s.paused
+}
+
+// SetSpeed sets how many simulation ticks run per frame, clamped to
+// MinSpeed..MaxSpeed.
+func (s *Scheduler) SetSpeed(speed float64) {
+	s.speed = min(max(speed, MinSpeed), MaxSpeed)
+}
+
+func (s *Scheduler) Speed() float64 {
+	return s.speed
+}
+
+// Frame returns the number of simulation ticks to run for one frame.
+// Fractional speeds carry the remainder over to the next frame.
+func (s *Scheduler) Frame() int {
+	if s.paused {
+		return 0
+	}
+	s.budget += s.speed
+	n := int(s.budget)
+	s.budget -= float64(n)
+	return n
+}
+
+// Hold counts one tick spent in a step that lasts the given number of ticks
+// and reports whether the step is over.
+func (s *Scheduler) Hold(ticks int) bool {
+	s.held++
+	if s.held < ticks {
+		return false
+	}
+	s.held = 0
+	return true
+}
@@ -0,0 +1,48 @@
+package sim
+
+import "testing"
+
+func TestSchedulerFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(s *Scheduler)
+		frames int
+		want   int
+	}{
+		{"default", func(s *Scheduler) {}, 10, 10},
+		{"paused", func(s *Scheduler) { s.Pause() }, 10, 0},
+		{"resumed", func(s *Scheduler) { s.Pause(); s.Resume() }, 10, 10},
+		{"double speed", func(s *Scheduler) { s.SetSpeed(2) }, 10, 20},
+		{"quarter speed", func(s *Scheduler) { s.SetSpeed(0.25) }, 10, 2},
+		{"fractional speed", func(s *Scheduler) { s.SetSpeed(1.5) }, 10, 15},
+		{"clamped to MaxSpeed", func(s *Scheduler) { s.SetSpeed(100) }, 10, 80},
+		{"clamped to MinSpeed", func(s *Scheduler) { s.SetSpeed(0) }, 8, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler()
+			tt.setup(s)
+			got := 0
+			for range tt.frames {
+				got += s.Frame()
+			}
+			if got != tt.want {
+				t.Errorf("%d frames ran %d ticks, want %d", tt.frames, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerHold(t *testing.T) {
+	s := NewScheduler()
+	for round := range 2 {
+		for i := 1; i < 3; i++ {
+			if s.Hold(3) {
+				t.Fatalf("round %d: Hold(3) done after %d ticks", round, i)
+			}
+		}
+		if !s.Hold(3) {
+			t.Fatalf("round %d: Hold(3) not done after 3 ticks", round)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package sim models the Spanner query animations as plain data.
+//
+// A Scenario holds the splits and rows of one animation and an Engine runs it:
+// the engine keeps the in-flight packets and the current step and is advanced
+// one frame at a time with Tick. Nothing here touches a window, so the
+// sequence of RPCs a query strategy issues can be inspected without rendering
+// anything.
+package sim
+
+type User struct {
+	UserID int
+	Name   string
+}
+
+type Order struct {
+	OrderID int
+	UserID  int
+	Item    string
+	Price   int
+}
+
+type IndexEntry struct {
+	UserID  int
+	OrderID int
+
+	// Item and Price are only set in an index that stores them (STORING).
+	Item  string
+	Price int
+}
+
+type JoinedData struct {
+	User  User
+	Order Order
+
+	// Null marks a LEFT OUTER row of a user without orders, whose Order
+	// columns are NULL.
+	Null bool
+}
+
+// ItemRow is a row of the small Items table of JOIN10.
+type ItemRow struct {
+	Item  string
+	Price int
+}
+
+// OrderItem is an Order joined with its ItemRow.
+type OrderItem struct {
+	Order Order
+	Item  ItemRow
+
+	// Null marks a LEFT OUTER row of an order without an item, whose Item
+	// columns are NULL.
+	Null bool
+}
+
+// AggregationResult is the state of one group. Key holds the values of the
+// GROUP BY columns.
+type AggregationResult struct {
+	Key []string
+	Partial
+}
+
+// Table names used in Endpoint.
+const (
+	TableUsers   = "Users"
+	TableOrders  = "Orders"
+	TableIndex   = "Index"
+	TableMidTier = "MidTier"
+	TableTopTier = "TopTier"
+
+	// TableCoordinator is the server that runs a hash join. Its splits are
+	// the buckets of the hash table.
+	TableCoordinator = "Coordinator"
+
+	// TableItems is the small table JOIN10 broadcasts to every Order split.
+	TableItems = "Items"
+)
+
+// Endpoint identifies a row in a split that a packet travels from or to.
+// Aggregation tiers have no rows and always use Row 0.
+type Endpoint struct {
+	Table string
+	Split int
+	Row   int
+}
+
+// Cursor is the position of a row in a table split over several machines.
+type Cursor struct {
+	Split int
+	Row   int
+}
+
+// RPC records a packet sent from one split to another.
+type RPC struct {
+	Tick int
+	From Endpoint
+	To   Endpoint
+}
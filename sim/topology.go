@@ -0,0 +1,86 @@
+package sim
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Topology is the number of splits of each table and, for the scenarios
+// that merge through aggregation tiers, the fan-in of the aggregation
+// servers. A zero count keeps the scenario's default.
+type Topology struct {
+	Users  int
+	Orders int
+	Index  int
+	FanIn  int
+}
+
+// TopologyScenario is a Scenario whose tables can be split over any number
+// of splits.
+type TopologyScenario interface {
+	Scenario
+
+	// SetTopology checks t and makes Setup generate data with that many
+	// splits.
+	SetTopology(t Topology) error
+}
+
+// withDefaults returns t with every zero count taken from def.
+func (t Topology) withDefaults(def Topology) Topology {
+	if t.Users == 0 {
+		t.Users = def.Users
+	}
+	if t.Orders == 0 {
+		t.Orders = def.Orders
+	}
+	if t.Index == 0 {
+		t.Index = def.Index
+	}
+	if t.FanIn == 0 {
+		t.FanIn = def.FanIn
+	}
+	return t
+}
+
+// checkTopology checks that t only sets the split counts of the given tables
+// and that none of them is negative. The fan-in can only be set if tables
+// include TableMidTier.
+func checkTopology(scenario string, t Topology, tables ...string) error {
+	if t.FanIn != 0 {
+		if !slices.Contains(tables, TableMidTier) {
+			return fmt.Errorf("%s has no aggregation tiers", scenario)
+		}
+		if t.FanIn < 2 {
+			return fmt.Errorf("invalid fan-in %d, want at least 2", t.FanIn)
+		}
+	}
+	counts := []struct {
+		table string
+		n     int
+	}{
+		{TableUsers, t.Users},
+		{TableOrders, t.Orders},
+		{TableIndex, t.Index},
+	}
+	for _, c := range counts {
+		if c.n < 0 {
+			return fmt.Errorf("invalid number of %s splits %d", c.table, c.n)
+		}
+		if c.n == 0 {
+			continue
+		}
+		if !slices.Contains(tables, c.table) {
+			return fmt.Errorf("%s has no %s table to split", scenario, c.table)
+		}
+	}
+	return nil
+}
+
+// splitEvenly cuts rows into n splits whose sizes differ by at most one.
+func splitEvenly[T any](rows []T, n int) [][]T {
+	splits := make([][]T, n)
+	for i := range splits {
+		splits[i] = rows[i*len(rows)/n : (i+1)*len(rows)/n]
+	}
+	return splits
+}